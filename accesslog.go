@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// statusRecorder records the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// accessLog logs each request served by h, at the info level with
+// -web.access-log and at the debug level otherwise, so that the scrapers and
+// their frequency can be found
+func (a *app) accessLog(h http.Handler) http.Handler {
+	level := zerolog.DebugLevel
+	if a.accessLogs {
+		level = zerolog.InfoLevel
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+
+		a.logger.WithLevel(level).
+			Str("remote_addr", r.RemoteAddr).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", rec.status).
+			Float64("duration_seconds", time.Since(start).Seconds()).
+			Str("user_agent", r.UserAgent()).
+			Msg("request served")
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// parseCIDRs parses comma-separated networks in CIDR notation. A single IP is
+// taken as the network holding only that IP.
+func parseCIDRs(s string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(s, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP or network %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// allowNetworks answers 403 to the requests coming from outside the networks
+// of -web.allowed-cidrs, when it is set, and serves the others with h. The
+// liveness and readiness probes are always served.
+func (a *app) allowNetworks(h http.Handler) http.Handler {
+	if len(a.allowedNetworks) == 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unauthenticatedPaths[r.URL.Path] || a.allowed(r.RemoteAddr) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		a.logger.Debug().Str("remote_addr", r.RemoteAddr).Str("path", r.URL.Path).Msg("request from a network not allowed")
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
+
+// allowed reports whether the client at addr belongs to an allowed network
+func (a *app) allowed(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	// strip the zone of link-local IPv6 addresses
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range a.allowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
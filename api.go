@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/collector"
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+)
+
+// apiMonitors is the body of /api/v1/monitors
+type apiMonitors struct {
+	Monitors []apiMonitor `json:"monitors"`
+}
+
+// apiMonitor is the current state of a monitor
+type apiMonitor struct {
+	ID                  int                `json:"id"`
+	FriendlyName        string             `json:"friendly_name"`
+	URL                 string             `json:"url"`
+	Type                string             `json:"type"`
+	Status              string             `json:"status"`
+	IntervalSeconds     float64            `json:"interval_seconds"`
+	Tags                []string           `json:"tags"`
+	ResponseTimeSeconds *float64           `json:"response_time_seconds,omitempty"`
+	LastCheck           *time.Time         `json:"last_check,omitempty"`
+	UptimeRatios        map[string]float64 `json:"uptime_ratios"`
+}
+
+// monitorsHandler serves the current state of the monitors of the main
+// account, or of the account named by the account query parameter, in JSON,
+// for the tools that do not query Prometheus. It answers 503 when the
+// monitors cannot be served.
+func (a *app) monitorsHandler(w http.ResponseWriter, r *http.Request) {
+	acc := a.lookupAccount(w, r)
+	if acc == nil {
+		return
+	}
+
+	states, err := acc.collector.Monitors(r.Context())
+	if err != nil {
+		http.Error(w, logger.Redact(err.Error()), http.StatusServiceUnavailable)
+		return
+	}
+	body := apiMonitors{Monitors: make([]apiMonitor, 0, len(states))}
+	for _, state := range states {
+		body.Monitors = append(body.Monitors, newAPIMonitor(state))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		a.logger.Error().Err(err).Msg("cannot write /api/v1/monitors answer")
+	}
+}
+
+// newAPIMonitor returns the JSON version of the state of a monitor
+func newAPIMonitor(state collector.MonitorState) apiMonitor {
+	monitor := apiMonitor{
+		ID:              state.ID,
+		FriendlyName:    state.FriendlyName,
+		URL:             state.URL,
+		Type:            state.Type,
+		Status:          state.Status,
+		IntervalSeconds: state.Interval.Seconds(),
+		Tags:            state.Tags,
+		LastCheck:       timeOrNil(state.LastCheck),
+		UptimeRatios:    state.UptimeRatios,
+	}
+	if monitor.Tags == nil {
+		monitor.Tags = []string{}
+	}
+	if state.ResponseTime > 0 {
+		rt := state.ResponseTime.Seconds()
+		monitor.ResponseTimeSeconds = &rt
+	}
+	return monitor
+}
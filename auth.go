@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/eze-kiel/uptimerobot-exporter/config"
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// unauthenticatedPaths are the paths served without authentication, so
+// that liveness and readiness probes keep working
+var unauthenticatedPaths = map[string]bool{
+	"/health":  true,
+	"/-/ready": true,
+}
+
+// dummyHash is compared with the passwords of unknown users, so that they take
+// as long to reject as the wrong passwords of known users
+var dummyHash = []byte("$2y$10$QOauhQNbBCuQDKes6eFzPeMqBSjb7Mr5DUmpZ/VcEd00UAV/LDeSi")
+
+// checkDebugAPIAuth makes sure that /debug/api, which exposes the monitors
+// and the account details, is only served to authenticated clients, given
+// the configuration cfg and the bearer token
+func (a *app) checkDebugAPIAuth(cfg *config.Config, bearerToken string) error {
+	if !a.debugAPI || len(cfg.BasicAuthUsers) > 0 || bearerToken != "" {
+		return nil
+	}
+	if a.webConfigFile != "" {
+		users, err := webConfigUsers(a.webConfigFile)
+		if err != nil {
+			return err
+		}
+		if users {
+			return nil
+		}
+	}
+	return errors.New("-web.enable-debug-api requires authentication, set basic_auth_users, -web.bearer-token or the users of -web.config.file")
+}
+
+// authenticate requires the bearer token of -web.bearer-token or the
+// credentials of one of the basic_auth_users of the configuration file to
+// serve the requests with h, when any is set
+func (a *app) authenticate(h http.Handler) http.Handler {
+	// bcrypt is slow by design, so the credentials already checked are
+	// remembered by their hash
+	var valid sync.Map
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.mu.RLock()
+		users, token := a.authUsers, a.bearerToken
+		a.mu.RUnlock()
+		if (len(users) == 0 && token == "") || unauthenticatedPaths[r.URL.Path] {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if token != "" {
+			auth := r.Header.Get("Authorization")
+			if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") &&
+				subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) == 1 {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		user, password, ok := r.BasicAuth()
+		if ok && len(users) > 0 {
+			hash, known := users[user]
+			if !known {
+				hash = string(dummyHash)
+			}
+			key := sha256.Sum256([]byte(user + "\x00" + hash + "\x00" + password))
+			if _, cached := valid.Load(key); cached && known {
+				h.ServeHTTP(w, r)
+				return
+			}
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil && known {
+				valid.Store(key, true)
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if len(users) > 0 {
+			w.Header().Add("WWW-Authenticate", `Basic realm="uptimerobot-exporter"`)
+		}
+		if token != "" {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="uptimerobot-exporter"`)
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// resolveBearerToken returns the bearer token given with -web.bearer-token or
+// held by -web.bearer-token-file, which is read again on reload
+func (a *app) resolveBearerToken() (string, error) {
+	token := a.webBearerToken
+	if a.webBearerTokenFile != "" {
+		content, err := ioutil.ReadFile(a.webBearerTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("cannot read bearer token: %w", err)
+		}
+		token = strings.TrimSpace(string(content))
+		if token == "" {
+			return "", fmt.Errorf("bearer token file %s is empty", a.webBearerTokenFile)
+		}
+	}
+	logger.AddSecret(token)
+	return token, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+)
+
+// checkConfig validates the flags, the configuration file and the monitor
+// labels file without serving anything, and optionally checks the API keys
+// against the Uptime Robot API. It prints every error found and exits with a
+// non-zero status if there is any, so that bad configurations are caught
+// before being deployed.
+func (a *app) checkConfig() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a.ctx = ctx
+
+	if errs := a.configErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, "error:", logger.Redact(err.Error()))
+		}
+		os.Exit(1)
+	}
+	fmt.Println("configuration OK")
+}
+
+// configErrors returns the errors found in the configuration. The flags have
+// already been validated while being parsed.
+func (a *app) configErrors() []error {
+	cfg, err := a.readConfig()
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	if _, err := a.monitorLabels(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := monitorOverrides(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := a.monitorFilters(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if bearerToken, err := a.resolveBearerToken(); err != nil {
+		errs = append(errs, err)
+	} else if err := a.checkDebugAPIAuth(cfg, bearerToken); err != nil {
+		errs = append(errs, err)
+	}
+	if !a.checkAPI {
+		return errs
+	}
+
+	apiKey, err := a.resolveAPIKey(cfg)
+	switch {
+	case err != nil:
+		errs = append(errs, err)
+	case apiKey == "" && len(cfg.Accounts) == 0:
+		errs = append(errs, fmt.Errorf("missing Uptime Robot API key"))
+	case apiKey != "":
+		if err := a.tryAPIKey(apiKey, "default"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, acc := range cfg.Accounts {
+		if err := a.tryAPIKey(acc.APIKey, acc.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// tryAPIKey makes sure that the API key of account is accepted by the API
+func (a *app) tryAPIKey(apiKey, account string) error {
+	client := uptimerobot.New(apiKey, a.clientOptions(account))
+	if err := client.CheckAPIKey(a.ctx); err != nil {
+		return fmt.Errorf("API key of account %s: %w", account, err)
+	}
+	return nil
+}
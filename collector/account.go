@@ -0,0 +1,111 @@
+package collector
+
+import (
+	"strconv"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// accountMetrics are the metrics of the account details
+type accountMetrics struct {
+	details            *prometheus.Desc
+	up                 *prometheus.Desc
+	down               *prometheus.Desc
+	paused             *prometheus.Desc
+	monitorLimit       *prometheus.Desc
+	minInterval        *prometheus.Desc
+	smsCredits         *prometheus.Desc
+	subscriptionExpiry *prometheus.Desc
+}
+
+// newAccountMetrics creates the account metrics, whose names start with prefix
+func newAccountMetrics(prefix string) accountMetrics {
+	return accountMetrics{
+		details: prometheus.NewDesc(
+			prefix+"account_details",
+			"Details of the Uptime Robot account",
+			[]string{"firstname", "email", "monitors_limit", "monitor_interval", "up_monitors", "down_monitors", "paused_monitors", "payment_period"}, nil,
+		),
+		up: prometheus.NewDesc(
+			prefix+"up_monitors",
+			"Up monitors",
+			nil, nil,
+		),
+		down: prometheus.NewDesc(
+			prefix+"down_monitors",
+			"Down monitors",
+			nil, nil,
+		),
+		paused: prometheus.NewDesc(
+			prefix+"paused_monitors",
+			"Paused monitors",
+			nil, nil,
+		),
+		monitorLimit: prometheus.NewDesc(
+			prefix+"account_monitor_limit",
+			"Maximum number of monitors of the account",
+			nil, nil,
+		),
+		minInterval: prometheus.NewDesc(
+			prefix+"account_min_interval_seconds",
+			"Shortest check interval allowed by the plan of the account",
+			nil, nil,
+		),
+		smsCredits: prometheus.NewDesc(
+			prefix+"account_sms_credits",
+			"SMS credits left on the account",
+			nil, nil,
+		),
+		subscriptionExpiry: prometheus.NewDesc(
+			prefix+"account_subscription_expiry_timestamp_seconds",
+			"Expiry date of the paid subscription of the account, as a Unix timestamp",
+			nil, nil,
+		),
+	}
+}
+
+// describe sends the descriptions of the account metrics
+func (m *accountMetrics) describe(ch chan<- *prometheus.Desc) {
+	ch <- m.details
+	ch <- m.up
+	ch <- m.down
+	ch <- m.paused
+	ch <- m.monitorLimit
+	ch <- m.minInterval
+	ch <- m.smsCredits
+	ch <- m.subscriptionExpiry
+}
+
+// collectAccount sends the metrics of the account details
+func (c *Collector) collectAccount(ch chan<- prometheus.Metric, account *uptimerobot.AccountDetails) {
+	acc := account.Account
+	ch <- prometheus.MustNewConstMetric(c.accountMetrics.up, prometheus.GaugeValue, float64(acc.UpMonitors))
+	ch <- prometheus.MustNewConstMetric(c.accountMetrics.down, prometheus.GaugeValue, float64(acc.DownMonitors))
+	ch <- prometheus.MustNewConstMetric(c.accountMetrics.paused, prometheus.GaugeValue, float64(acc.PausedMonitors))
+	ch <- prometheus.MustNewConstMetric(c.accountMetrics.monitorLimit, prometheus.GaugeValue, float64(acc.MonitorLimit))
+	// the API gives the interval in minutes
+	ch <- prometheus.MustNewConstMetric(c.accountMetrics.minInterval, prometheus.GaugeValue, float64(acc.MonitorInterval*60))
+	ch <- prometheus.MustNewConstMetric(c.accountMetrics.smsCredits, prometheus.GaugeValue, float64(acc.SmsCredits))
+
+	if c.opts.LegacyAccountDetails {
+		firstname, email := acc.Firstname, acc.Email
+		if c.opts.RedactAccountPII {
+			firstname, email = "", ""
+		}
+		ch <- prometheus.MustNewConstMetric(c.accountMetrics.details, prometheus.GaugeValue, 1,
+			firstname,
+			email,
+			strconv.Itoa(acc.MonitorLimit),
+			strconv.Itoa(acc.MonitorInterval),
+			strconv.Itoa(acc.UpMonitors),
+			strconv.Itoa(acc.DownMonitors),
+			strconv.Itoa(acc.PausedMonitors),
+			strconv.Itoa(acc.PaymentPeriod))
+	}
+
+	// free plans have no expiry date
+	if expiry := acc.SubscriptionExpiryDate; !expiry.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.accountMetrics.subscriptionExpiry, prometheus.GaugeValue, float64(expiry.Unix()))
+	}
+}
@@ -0,0 +1,518 @@
+package collector
+
+import (
+	"context"
+	"math/rand"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// fetchResult is the outcome of the last fetch of a kind of data
+type fetchResult struct {
+	duration time.Duration
+	success  bool
+
+	// lastSuccess and lastError are the dates of the last successful and
+	// failed fetches, lastErr the error of the last failed one
+	lastSuccess time.Time
+	lastError   time.Time
+	lastErr     error
+}
+
+// Collector exposes Uptime Robot data as Prometheus metrics. In on-demand
+// mode the API is queried each time the collector is scraped, otherwise the
+// data fetched by Run is served.
+type Collector struct {
+	ctx    context.Context
+	client *uptimerobot.Client
+	logger zerolog.Logger
+	opts   Options
+
+	mu       sync.RWMutex
+	account  *uptimerobot.AccountDetails
+	monitors map[int]uptimerobot.Monitor
+
+	// monitorMetrics are the per-monitor metrics, with the nameLabels
+	// extracted from the friendly names or set by the label mappings.
+	// extractedLabels holds their values, by monitor ID.
+	monitorMetrics  monitorMetrics
+	nameLabels      []string
+	extractedLabels map[int][]string
+
+	// the other metrics, whose names depend on the metrics prefix
+	accountMetrics       accountMetrics
+	mwindowMetrics       mwindowMetrics
+	monitorsByStatusDesc *prometheus.Desc
+	dataStaleDesc        *prometheus.Desc
+	scrapeDurationDesc   *prometheus.Desc
+	scrapeSuccessDesc    *prometheus.Desc
+
+	mwindows []uptimerobot.MWindow
+
+	// downEvents holds the down events counters of the monitors, by ID
+	downEvents map[int]*downEvents
+
+	// statuses holds the status transitions of the monitors, by ID
+	statuses map[int]*statusHistory
+
+	// responseTimes holds the latest response times of the monitors, by ID
+	responseTimes map[int]*samples
+
+	// histograms holds the response time histograms of the monitors, by ID
+	histograms map[int]*responseTimeHistogram
+
+	// consecutive failed fetches of each kind of data
+	accountFailures  int
+	monitorsFailures int
+	mwindowsFailures int
+
+	// fetches holds the outcome of the last fetch of each kind of data
+	fetches map[string]fetchResult
+
+	// ready is closed once the first fetches started by Run are over
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	// loops holds the state of the polling loops started by Run, by name
+	loops map[string]loopState
+}
+
+// loopState is the state of a polling loop
+type loopState struct {
+	// period is the maximum time between two rounds of the loop
+	period time.Duration
+	// last is the time the last round of the loop ended
+	last time.Time
+}
+
+// Options tunes the behaviour of a Collector
+type Options struct {
+	// OnDemand makes the collector query the API on each collection
+	OnDemand bool
+	// Filters select the exported monitors, and can be replaced at runtime.
+	// They can be nil.
+	Filters *MonitorFilters
+	// Overrides tune the export of single monitors. They can be nil.
+	Overrides *MonitorOverrides
+	// SkipPaused leaves the paused monitors out of the per-monitor metrics.
+	// They are still counted by the aggregate metrics.
+	SkipPaused bool
+	// MaxFailedFetches is the number of consecutive failed fetches after
+	// which the data is dropped instead of being served, 0 means never
+	MaxFailedFetches int
+	// ResponseTimeWindow is the period over which the rolling response time
+	// quantiles are computed, 0 disables them
+	ResponseTimeWindow time.Duration
+	// LegacyAccountDetails exports uptimerobot_account_details, holding the
+	// account numbers in labels
+	LegacyAccountDetails bool
+	// ResponseTimeTimestamps exports the latest response time with the date
+	// of the check it comes from instead of the scrape time
+	ResponseTimeTimestamps bool
+	// ResponseTimeHistogram exports a histogram of the response times of the
+	// checks, with the latest check of each monitor as exemplar
+	ResponseTimeHistogram bool
+	// RedactAccountPII leaves the firstname and email labels of
+	// uptimerobot_account_details empty
+	RedactAccountPII bool
+	// LegacyMonitorLabels exports uptimerobot_monitors_status and the
+	// latest response time without the monitor_id label
+	LegacyMonitorLabels bool
+	// LowChurnLabels drops the interval and type labels of
+	// uptimerobot_monitors_status and of the latest response time, so that
+	// editing a monitor does not create new series
+	LowChurnLabels bool
+	// LegacyResponseTimeUnits exports the response times in milliseconds,
+	// under their former names without the _seconds suffix
+	LegacyResponseTimeUnits bool
+	// NameLabels are regular expressions matched against the friendly names
+	// of the monitors, whose named capture groups become labels of the
+	// per-monitor metrics. They must be checked with CheckNameLabels.
+	NameLabels []*regexp.Regexp
+	// LabelMappings set labels of the per-monitor metrics by monitor ID or
+	// URL. They can be nil.
+	LabelMappings *LabelMappings
+	// Labels are the monitor attributes labelling the per-monitor metrics
+	// other than uptimerobot_monitor_info, as returned by ParseLabels. When
+	// nil, the metrics are labelled with the monitor ID, and
+	// uptimerobot_monitors_status and the latest response time with their
+	// historical labels. It takes precedence over LegacyMonitorLabels and
+	// LowChurnLabels.
+	Labels []string
+	// MetricsPrefix is the prefix of the metric names,
+	// uptimerobot.DefaultMetricsPrefix when empty
+	MetricsPrefix string
+}
+
+// New creates a new collector querying the API with the given client. The API
+// calls made while collecting are cancelled when ctx is done.
+func New(ctx context.Context, client *uptimerobot.Client, logger zerolog.Logger, opts Options) *Collector {
+	nameLabels := append(nameLabelNames(opts.NameLabels), opts.LabelMappings.Names()...)
+	if opts.Labels != nil {
+		opts.LegacyMonitorLabels = false
+	}
+	if opts.MetricsPrefix == "" {
+		opts.MetricsPrefix = uptimerobot.DefaultMetricsPrefix
+	}
+	prefix := opts.MetricsPrefix
+	return &Collector{
+		ctx:            ctx,
+		client:         client,
+		logger:         logger,
+		opts:           opts,
+		monitorMetrics: newMonitorMetrics(opts, nameLabels),
+		nameLabels:     nameLabels,
+		accountMetrics: newAccountMetrics(prefix),
+		mwindowMetrics: newMWindowMetrics(prefix),
+		monitorsByStatusDesc: prometheus.NewDesc(
+			prefix+"monitors_by_status",
+			"Number of monitors in each status, counted from the monitors list",
+			[]string{"status"}, nil,
+		),
+		dataStaleDesc: prometheus.NewDesc(
+			prefix+"data_stale",
+			"Whether the last fetch of the data failed, the served values being older or dropped (1) or not (0)",
+			[]string{"data"}, nil,
+		),
+		scrapeDurationDesc: prometheus.NewDesc(
+			prefix+"scrape_duration_seconds",
+			"Duration of the last fetch of the data from the API",
+			[]string{"collector"}, nil,
+		),
+		scrapeSuccessDesc: prometheus.NewDesc(
+			prefix+"scrape_success",
+			"Whether the last fetch of the data from the API succeeded (1) or not (0)",
+			[]string{"collector"}, nil,
+		),
+		ready:   make(chan struct{}),
+		fetches: make(map[string]fetchResult),
+		loops:   make(map[string]loopState),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.accountMetrics.describe(ch)
+	ch <- c.monitorsByStatusDesc
+	c.monitorMetrics.describe(ch)
+	c.mwindowMetrics.describe(ch)
+	ch <- c.dataStaleDesc
+	ch <- c.scrapeDurationDesc
+	ch <- c.scrapeSuccessDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	if c.opts.OnDemand {
+		// the failed fetches are logged and recorded like in polling mode,
+		// the data fetched before being served as stale
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if !c.client.MonitorScoped() {
+				c.fetchAccountDetails(c.ctx)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if !c.client.MonitorScoped() {
+				c.fetchMWindows(c.ctx)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			c.fetchMonitors(c.ctx)
+		}()
+		wg.Wait()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.client.MonitorScoped() {
+		ch <- prometheus.MustNewConstMetric(c.dataStaleDesc, prometheus.GaugeValue, boolToFloat(c.accountFailures > 0), "account")
+		ch <- prometheus.MustNewConstMetric(c.dataStaleDesc, prometheus.GaugeValue, boolToFloat(c.mwindowsFailures > 0), "maintenance_windows")
+	}
+	ch <- prometheus.MustNewConstMetric(c.dataStaleDesc, prometheus.GaugeValue, boolToFloat(c.monitorsFailures > 0), "monitors")
+	for name, fetch := range c.fetches {
+		ch <- prometheus.MustNewConstMetric(c.scrapeDurationDesc, prometheus.GaugeValue, fetch.duration.Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(c.scrapeSuccessDesc, prometheus.GaugeValue, boolToFloat(fetch.success), name)
+	}
+
+	if c.account != nil {
+		c.collectAccount(ch, c.account)
+	}
+	c.collectMonitors(ch)
+	if c.opts.LegacyMonitorLabels {
+		c.collectLegacyMonitors(ch, c.monitors)
+	}
+	if c.monitors != nil {
+		c.collectMonitorsByStatus(ch, c.monitors)
+	}
+	for _, w := range c.mwindows {
+		c.collectMWindow(ch, w)
+	}
+}
+
+// Intervals holds the polling interval of each kind of data
+type Intervals struct {
+	Account  time.Duration
+	Monitors time.Duration
+	// Jitter is the maximum random delay added before each fetch, so that
+	// exporters started together do not call the API at the same time
+	Jitter time.Duration
+}
+
+// Run polls the API until ctx is done, starting right away. It is meant to be
+// used when the collector is not in on-demand mode.
+func (c *Collector) Run(ctx context.Context, intervals Intervals) {
+	var initial sync.WaitGroup
+	initial.Add(1)
+	go c.loop(ctx, "monitors", intervals.Monitors, intervals.Jitter, c.fetchMonitors, initial.Done)
+	if !c.client.MonitorScoped() {
+		initial.Add(2)
+		go c.loop(ctx, "account", intervals.Account, intervals.Jitter, c.fetchAccountDetails, initial.Done)
+		go c.loop(ctx, "maintenance_windows", intervals.Monitors, intervals.Jitter, c.fetchMWindows, initial.Done)
+	}
+	go func() {
+		initial.Wait()
+		c.readyOnce.Do(func() { close(c.ready) })
+	}()
+}
+
+// Ready reports whether the collector has data to serve, i.e. if it is in
+// on-demand mode or if the first fetches started by Run are over
+func (c *Collector) Ready() bool {
+	if c.opts.OnDemand {
+		return true
+	}
+	select {
+	case <-c.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// loop calls fetch right away, then done, and then fetch again every
+// interval. Each call is delayed by a random duration up to jitter. The end of
+// each round is recorded under name for Stalled.
+func (c *Collector) loop(ctx context.Context, name string, interval, jitter time.Duration, fetch func(context.Context) error, done func()) {
+	c.tick(name, jitter)
+	timer := time.NewTimer(randomDelay(jitter))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		done()
+		return
+	case <-timer.C:
+	}
+	fetch(ctx)
+	done()
+
+	c.tick(name, interval+jitter)
+	timer.Reset(interval + randomDelay(jitter))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			timer.Reset(interval + randomDelay(jitter))
+			if wait := c.client.RateLimitedFor(); wait > 0 {
+				c.logger.Info().Msgf("rate limited by the API, skipping this fetch (%s left)", wait.Round(time.Second))
+			} else {
+				fetch(ctx)
+			}
+			c.tick(name, interval+jitter)
+		}
+	}
+}
+
+// tick records the end of a round of the loop named name, the next one being
+// expected to end within period plus the duration of a fetch
+func (c *Collector) tick(name string, period time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loops[name] = loopState{period: period, last: time.Now()}
+}
+
+// Stalled returns the names of the polling loops started by Run whose last
+// round ended more than grace after the next one was due, which happens when
+// a fetch hangs
+func (c *Collector) Stalled(grace time.Duration) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var stalled []string
+	for name, loop := range c.loops {
+		if time.Since(loop.last) > loop.period+grace {
+			stalled = append(stalled, name)
+		}
+	}
+	sort.Strings(stalled)
+	return stalled
+}
+
+var (
+	// delays is seeded so that several exporter instances do not draw the
+	// same delays
+	delaysMu sync.Mutex
+	delays   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// randomDelay returns a random duration in [0, max)
+func randomDelay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	delaysMu.Lock()
+	defer delaysMu.Unlock()
+	return time.Duration(delays.Int63n(int64(max)))
+}
+
+// fetchAccountDetails queries the account details and stores them for the
+// next collection
+func (c *Collector) fetchAccountDetails(ctx context.Context) error {
+	c.logger.Info().Msg("fetching account details")
+	start := time.Now()
+	account, err := c.client.GetAccountDetails(ctx)
+	c.recordFetch("account", start, err)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("failed to fetch account details")
+		c.mu.Lock()
+		c.accountFailures++
+		if c.expired(c.accountFailures) && c.account != nil {
+			c.logger.Warn().Msgf("account details not fetched for %d cycles, dropping them", c.accountFailures)
+			c.account = nil
+		}
+		c.mu.Unlock()
+		return err
+	}
+
+	c.logger.Debug().Msg("updating account details metrics")
+	c.mu.Lock()
+	c.account = account
+	c.accountFailures = 0
+	c.mu.Unlock()
+	return nil
+}
+
+// fetchMonitors queries the monitors and stores them by ID for the next
+// collection. Monitors whose ID is not returned anymore are dropped along with
+// their metrics, whatever their name.
+func (c *Collector) fetchMonitors(ctx context.Context) error {
+	c.logger.Info().Msg("fetching monitors")
+	start := time.Now()
+	filters := c.opts.Filters.get()
+	monitors, err := c.client.GetMonitors(ctx, c.monitorsQuery(filters))
+	c.recordFetch("monitors", start, err)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("failed to fetch monitors")
+		c.mu.Lock()
+		c.monitorsFailures++
+		if c.expired(c.monitorsFailures) && c.monitors != nil {
+			c.logger.Warn().Msgf("monitors not fetched for %d cycles, dropping their metrics", c.monitorsFailures)
+			c.monitors = nil
+		}
+		c.mu.Unlock()
+		return err
+	}
+	c.logger.Debug().Msgf("fetched %d monitors", len(monitors))
+
+	// pages fetched while monitors are created or deleted can overlap, so the
+	// same monitor may be returned twice
+	byID := make(map[int]uptimerobot.Monitor, len(monitors))
+	for _, m := range monitors {
+		m, enabled := c.applyOverride(m)
+		if !enabled || !filters.selected(m) {
+			continue
+		}
+		c.logger.Debug().Msgf("updating monitors metrics for %s: %f (rtt count %d)", m.FriendlyName, float64(m.Status), len(m.ResponseTimes))
+		byID[m.ID] = m
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, old := range c.monitors {
+		if _, ok := byID[id]; !ok {
+			c.logger.Debug().Msgf("monitor %d (%s) does not exist anymore, its metrics have been deleted", id, old.FriendlyName)
+		}
+	}
+	c.monitors = byID
+	c.extractedLabels = make(map[int][]string, len(byID))
+	for id, m := range byID {
+		values := extractNameLabels(c.opts.NameLabels, c.nameLabels, m.FriendlyName)
+		c.opts.LabelMappings.apply(c.nameLabels, values, m)
+		c.extractedLabels[id] = values
+	}
+	c.countDownEvents(byID)
+	c.countTransitions(byID)
+	c.recordResponseTimes(byID)
+	c.observeResponseTimes(byID)
+	c.monitorsFailures = 0
+	return nil
+}
+
+// fetchMWindows queries the maintenance windows and stores them for the next
+// collection
+func (c *Collector) fetchMWindows(ctx context.Context) error {
+	c.logger.Info().Msg("fetching maintenance windows")
+	start := time.Now()
+	mwindows, err := c.client.GetMWindows(ctx)
+	c.recordFetch("maintenance_windows", start, err)
+	if err != nil {
+		// a partial failure, the monitors being served without them
+		c.logger.Warn().Err(err).Msg("failed to fetch maintenance windows")
+		c.mu.Lock()
+		c.mwindowsFailures++
+		if c.expired(c.mwindowsFailures) && c.mwindows != nil {
+			c.logger.Warn().Msgf("maintenance windows not fetched for %d cycles, dropping their metrics", c.mwindowsFailures)
+			c.mwindows = nil
+		}
+		c.mu.Unlock()
+		return err
+	}
+	c.logger.Debug().Msgf("fetched %d maintenance windows", len(mwindows))
+
+	c.mu.Lock()
+	c.mwindows = mwindows
+	c.mwindowsFailures = 0
+	c.mu.Unlock()
+	return nil
+}
+
+// recordFetch stores the outcome of a fetch started at start
+func (c *Collector) recordFetch(name string, start time.Time, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fetch := c.fetches[name]
+	fetch.duration = time.Since(start)
+	fetch.success = err == nil
+	if err != nil {
+		fetch.lastError = time.Now()
+		fetch.lastErr = err
+	} else {
+		fetch.lastSuccess = time.Now()
+	}
+	c.fetches[name] = fetch
+}
+
+// expired reports whether data that failed to be fetched the given number of
+// consecutive times must be dropped
+func (c *Collector) expired(failures int) bool {
+	return c.opts.MaxFailedFetches > 0 && failures >= c.opts.MaxFailedFetches
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,208 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+// answers are successful answers of the API methods
+var answers = map[string]string{
+	"getAccountDetails": `{"stat":"ok","account":{"email":"ops@example.com","monitor_limit":50,"up_monitors":1}}`,
+	"getMonitors":       `{"stat":"ok","pagination":{"offset":0,"limit":50,"total":1},"monitors":[{"id":1,"friendly_name":"site","url":"https://example.com","type":1,"interval":300,"status":2}]}`,
+	"getMWindows":       `{"stat":"ok","pagination":{"offset":0,"limit":50,"total":1},"mwindows":[{"id":1,"type":1,"friendly_name":"upgrade","duration":60,"status":1}]}`,
+}
+
+// newTestCollector returns an on-demand collector calling a server that
+// answers with answers, failing the methods for which fail returns true
+func newTestCollector(t *testing.T, opts Options, fail func(method string) bool) *Collector {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := path.Base(r.URL.Path)
+		if fail(method) {
+			http.Error(w, "oops", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(answers[method]))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := uptimerobot.New("key", uptimerobot.Options{BaseURL: srv.URL, Logger: zerolog.Nop()})
+	opts.OnDemand = true
+	return New(context.Background(), client, zerolog.Nop(), opts)
+}
+
+// scrape serves the metrics of c as /metrics would, failing the test unless
+// they are served with a 200
+func scrape(t *testing.T, c *Collector) string {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	w := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+	}
+	return w.Body.String()
+}
+
+// checkMetrics fails the test if a line of want is missing from metrics, or
+// if a line of unwanted is found in them
+func checkMetrics(t *testing.T, metrics string, want, unwanted []string) {
+	t.Helper()
+	for _, line := range want {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("%s not found in:\n%s", line, metrics)
+		}
+	}
+	for _, line := range unwanted {
+		if strings.Contains(metrics, line+"\n") {
+			t.Errorf("%s found in:\n%s", line, metrics)
+		}
+	}
+}
+
+func TestOnDemandScrapeSuccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		failed string
+		want   []string
+	}{
+		{
+			name: "success",
+			want: []string{
+				`uptimerobot_scrape_success{collector="account"} 1`,
+				`uptimerobot_scrape_success{collector="maintenance_windows"} 1`,
+				`uptimerobot_scrape_success{collector="monitors"} 1`,
+			},
+		},
+		{
+			name:   "account details failed",
+			failed: "getAccountDetails",
+			want: []string{
+				`uptimerobot_scrape_success{collector="account"} 0`,
+				`uptimerobot_scrape_success{collector="maintenance_windows"} 1`,
+				`uptimerobot_scrape_success{collector="monitors"} 1`,
+			},
+		},
+		{
+			name:   "monitors failed",
+			failed: "getMonitors",
+			want: []string{
+				`uptimerobot_scrape_success{collector="account"} 1`,
+				`uptimerobot_scrape_success{collector="maintenance_windows"} 1`,
+				`uptimerobot_scrape_success{collector="monitors"} 0`,
+			},
+		},
+		{
+			name:   "maintenance windows failed",
+			failed: "getMWindows",
+			want: []string{
+				`uptimerobot_scrape_success{collector="account"} 1`,
+				`uptimerobot_scrape_success{collector="maintenance_windows"} 0`,
+				`uptimerobot_scrape_success{collector="monitors"} 1`,
+				`uptimerobot_monitor_interval_seconds{monitor_id="1"} 300`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector(t, Options{}, func(method string) bool {
+				return method == tt.failed
+			})
+			checkMetrics(t, scrape(t, c), tt.want, nil)
+		})
+	}
+}
+
+func TestOnDemandStaleData(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxFails int
+		// failures is the number of failed scrapes following a successful
+		// one
+		failures int
+		want     []string
+		unwanted []string
+	}{
+		{
+			name:     "fresh",
+			maxFails: 2,
+			want:     []string{`uptimerobot_data_stale{data="monitors"} 0`, `uptimerobot_monitor_interval_seconds{monitor_id="1"} 300`},
+		},
+		{
+			name:     "stale",
+			maxFails: 2,
+			failures: 1,
+			want:     []string{`uptimerobot_data_stale{data="monitors"} 1`, `uptimerobot_monitor_interval_seconds{monitor_id="1"} 300`},
+		},
+		{
+			name:     "never dropped",
+			failures: 3,
+			want:     []string{`uptimerobot_data_stale{data="monitors"} 1`, `uptimerobot_monitor_interval_seconds{monitor_id="1"} 300`},
+		},
+		{
+			name:     "dropped",
+			maxFails: 2,
+			failures: 2,
+			want:     []string{`uptimerobot_data_stale{data="monitors"} 1`},
+			unwanted: []string{`uptimerobot_monitor_interval_seconds{monitor_id="1"} 300`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing := false
+			c := newTestCollector(t, Options{MaxFailedFetches: tt.maxFails}, func(method string) bool {
+				return failing && method == "getMonitors"
+			})
+			metrics := scrape(t, c)
+			failing = true
+			for i := 0; i < tt.failures; i++ {
+				metrics = scrape(t, c)
+			}
+			checkMetrics(t, metrics, tt.want, tt.unwanted)
+		})
+	}
+}
+
+func TestOverriddenMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		metrics  []string
+		want     []string
+		unwanted []string
+	}{
+		{
+			name: "all metrics",
+			want: []string{
+				`uptimerobot_monitor_interval_seconds{monitor_id="1"} 300`,
+				`uptimerobot_monitor_state{monitor_id="1",state="up"} 1`,
+				`uptimerobot_monitor_down_events_total{monitor_id="1"} 0`,
+			},
+		},
+		{
+			name:    "selected metrics",
+			metrics: []string{"monitor_interval_seconds"},
+			want:    []string{`uptimerobot_monitor_interval_seconds{monitor_id="1"} 300`},
+			unwanted: []string{
+				`uptimerobot_monitor_state{monitor_id="1",state="up"} 1`,
+				`uptimerobot_monitor_down_events_total{monitor_id="1"} 0`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides := NewMonitorOverrides(map[int]MonitorOverride{1: {Metrics: tt.metrics}})
+			c := newTestCollector(t, Options{Overrides: overrides}, func(string) bool { return false })
+			checkMetrics(t, scrape(t, c), tt.want, tt.unwanted)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package collector
+
+import "github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+
+// eventsLogsLimit is the number of logs fetched per monitor on each poll.
+// Down events are counted from the logs, so more down events than this
+// between two polls are undercounted.
+const eventsLogsLimit = 10
+
+// downEvents counts the down events of a monitor seen in its logs
+type downEvents struct {
+	count float64
+	// last is the date of the latest down event counted
+	last int
+}
+
+// countDownEvents updates the down events counters from the logs of the
+// fetched monitors. Counters start at 0 when a monitor is first seen, the
+// events logged before being ignored. It must be called with c.mu held.
+func (c *Collector) countDownEvents(monitors map[int]uptimerobot.Monitor) {
+	counters := make(map[int]*downEvents, len(monitors))
+	for id, m := range monitors {
+		counter, known := c.downEvents[id]
+		if !known {
+			counter = &downEvents{}
+		}
+		latest := counter.last
+		for _, l := range m.Logs {
+			if l.Type != uptimerobot.LogTypeDown {
+				continue
+			}
+			if l.Datetime > latest {
+				latest = l.Datetime
+			}
+			if known && l.Datetime > counter.last {
+				counter.count++
+			}
+		}
+		counter.last = latest
+		counters[id] = counter
+	}
+	c.downEvents = counters
+}
+
+// transition is a change of status of a monitor
+type transition struct {
+	from, to int
+}
+
+// statusHistory tracks the status changes of a monitor
+type statusHistory struct {
+	last        int
+	transitions map[transition]float64
+}
+
+// countTransitions updates the status transitions counters of the fetched
+// monitors, comparing their status with the one of the previous fetch. It
+// must be called with c.mu held.
+func (c *Collector) countTransitions(monitors map[int]uptimerobot.Monitor) {
+	histories := make(map[int]*statusHistory, len(monitors))
+	for id, m := range monitors {
+		history, ok := c.statuses[id]
+		if !ok {
+			history = &statusHistory{last: m.Status, transitions: make(map[transition]float64)}
+		}
+		if m.Status != history.last {
+			history.transitions[transition{from: history.last, to: m.Status}]++
+			history.last = m.Status
+		}
+		histories[id] = history
+	}
+	c.statuses = histories
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+)
+
+// Filters select the exported monitors
+type Filters struct {
+	// IDs restricts the exported monitors to the given IDs when not empty
+	IDs []int
+	// Types restricts the exported monitors to the given types, as returned
+	// by ParseMonitorTypes, when not empty
+	Types []int
+	// Statuses restricts the exported monitors to the given statuses, as
+	// returned by ParseMonitorStatuses, when not empty
+	Statuses []int
+	// Search restricts the exported monitors to the ones whose URL or
+	// friendly name contains it when not empty
+	Search string
+	// Include, when set, restricts the exported monitors to the ones whose
+	// friendly name or URL it matches
+	Include *regexp.Regexp
+	// Tags restricts the exported monitors to the ones having at least one
+	// of the given Uptime Robot tags when not empty
+	Tags []string
+	// Exclude, when set, drops the monitors whose friendly name or URL it
+	// matches
+	Exclude *regexp.Regexp
+}
+
+// MonitorFilters holds the filters selecting the exported monitors, which
+// can be replaced at runtime
+type MonitorFilters struct {
+	mu      sync.RWMutex
+	filters Filters
+}
+
+// NewMonitorFilters returns monitor filters holding the given filters
+func NewMonitorFilters(filters Filters) *MonitorFilters {
+	return &MonitorFilters{filters: filters}
+}
+
+// Set replaces the filters. They are applied from the next fetch of the
+// monitors.
+func (f *MonitorFilters) Set(filters Filters) {
+	f.mu.Lock()
+	f.filters = filters
+	f.mu.Unlock()
+}
+
+// get returns the filters, none if f is nil
+func (f *MonitorFilters) get() Filters {
+	if f == nil {
+		return Filters{}
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.filters
+}
+
+// selected reports whether the monitor m passes the Include, Tags and Exclude
+// filters, the others being applied by the API
+func (f Filters) selected(m uptimerobot.Monitor) bool {
+	matches := func(re *regexp.Regexp) bool {
+		return re.MatchString(m.FriendlyName) || re.MatchString(m.URL)
+	}
+	if f.Include != nil && !matches(f.Include) {
+		return false
+	}
+	if len(f.Tags) > 0 && !hasTag(m, f.Tags) {
+		return false
+	}
+	return f.Exclude == nil || !matches(f.Exclude)
+}
+
+// hasTag reports whether the monitor m has one of the tags
+func hasTag(m uptimerobot.Monitor, tags []string) bool {
+	for _, tag := range m.Tags {
+		for _, name := range tags {
+			if tag.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// responseTimeBuckets are the upper bounds of the buckets of the response
+// time histograms, in seconds
+var responseTimeBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// responseTimeHistogram counts the response times of the checks of a monitor
+type responseTimeHistogram struct {
+	// counts holds the number of checks in each bucket, the last one being
+	// +Inf, not cumulated
+	counts []uint64
+	count  uint64
+	sum    float64
+	// latest is the latest check counted, exported as exemplar
+	latest sample
+}
+
+// observeResponseTimes counts the checks of the fetched monitors not counted
+// yet in their response time histograms. Histograms start empty when a
+// monitor is first seen, the checks made before being ignored. It must be
+// called with c.mu held.
+func (c *Collector) observeResponseTimes(monitors map[int]uptimerobot.Monitor) {
+	if !c.opts.ResponseTimeHistogram {
+		return
+	}
+	histograms := make(map[int]*responseTimeHistogram, len(monitors))
+	for id, m := range monitors {
+		h, known := c.histograms[id]
+		if !known {
+			h = &responseTimeHistogram{counts: make([]uint64, len(responseTimeBuckets)+1)}
+			h.latest.datetime = lastCheck(m)
+		}
+		// the response times come newest first
+		for i := len(m.ResponseTimes) - 1; i >= 0; i-- {
+			rt := m.ResponseTimes[i]
+			if rt.Datetime <= h.latest.datetime {
+				continue
+			}
+			value := float64(rt.Value) / 1000
+			bucket := len(responseTimeBuckets)
+			for b, bound := range responseTimeBuckets {
+				if value <= bound {
+					bucket = b
+					break
+				}
+			}
+			h.counts[bucket]++
+			h.count++
+			h.sum += value
+			h.latest = sample{datetime: rt.Datetime, value: value}
+		}
+		histograms[id] = h
+	}
+	c.histograms = histograms
+}
+
+// collectResponseTimeHistogram sends the response time histogram of a
+// monitor, with its latest check as exemplar
+func (c *Collector) collectResponseTimeHistogram(ch chan<- prometheus.Metric, m uptimerobot.Monitor) {
+	h, ok := c.histograms[m.ID]
+	if !ok {
+		return
+	}
+	// the histograms count seconds, converted to the unit of the metrics
+	scale := 1000 / c.monitorMetrics.responseTimeUnit
+	buckets := make(map[float64]uint64, len(h.counts))
+	var cumulated uint64
+	for i, count := range h.counts {
+		cumulated += count
+		bound := math.Inf(1)
+		if i < len(responseTimeBuckets) {
+			bound = responseTimeBuckets[i] * scale
+		}
+		buckets[bound] = cumulated
+	}
+	metric := prometheus.MustNewConstHistogram(c.monitorMetrics.responseTimeChecks.desc, h.count, h.sum*scale, buckets,
+		c.monitorLabelValues(c.monitorMetrics.responseTimeChecks, m)...)
+	if h.count == 0 {
+		ch <- metric
+		return
+	}
+	ch <- exemplarMetric{
+		Metric: metric,
+		value:  h.latest.value * scale,
+		time:   time.Unix(int64(h.latest.datetime), 0),
+		labels: map[string]string{"monitor_id": strconv.Itoa(m.ID)},
+	}
+}
+
+// exemplarMetric is a histogram with an exemplar on the bucket its value falls
+// in, exposed when the metrics are scraped in the OpenMetrics format
+type exemplarMetric struct {
+	prometheus.Metric
+	value  float64
+	time   time.Time
+	labels map[string]string
+}
+
+// Write implements prometheus.Metric
+func (m exemplarMetric) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+	exemplar := &dto.Exemplar{Value: proto.Float64(m.value), Timestamp: timestamppb.New(m.time)}
+	for name, value := range m.labels {
+		exemplar.Label = append(exemplar.Label, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+	}
+	for _, bucket := range out.GetHistogram().GetBucket() {
+		if m.value <= bucket.GetUpperBound() {
+			bucket.Exemplar = exemplar
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+)
+
+// check is a check of a monitor, at a date with a response time in
+// milliseconds
+type check struct {
+	datetime, value int
+}
+
+// monitorWithChecks returns the monitor with ID 1 having the given checks,
+// newest first as returned by the API
+func monitorWithChecks(t *testing.T, checks []check) uptimerobot.Monitor {
+	t.Helper()
+	rts := make([]string, len(checks))
+	for i, c := range checks {
+		rts[i] = fmt.Sprintf(`{"datetime":%d,"value":%d}`, c.datetime, c.value)
+	}
+	var m uptimerobot.Monitor
+	if err := json.Unmarshal([]byte(`{"id":1,"type":1,"response_times":[`+strings.Join(rts, ",")+`]}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestResponseTimeHistogram(t *testing.T) {
+	tests := []struct {
+		name   string
+		legacy bool
+		// fetches are the checks returned by each fetch of the monitor
+		fetches [][]check
+		count   uint64
+		sum     float64
+		// buckets are the cumulative counts by upper bound
+		buckets map[float64]uint64
+		// exemplarBound is the upper bound of the bucket holding the
+		// exemplar, 0 if there is none
+		exemplarBound float64
+		exemplarValue float64
+		exemplarTime  int64
+	}{
+		{
+			name:    "checks before the first fetch",
+			fetches: [][]check{{{100, 80}}},
+			buckets: map[float64]uint64{0.05: 0, 0.1: 0, 2.5: 0, 30: 0},
+		},
+		{
+			name:    "new checks",
+			fetches: [][]check{{{100, 80}}, {{300, 2000}, {200, 120}, {100, 80}}},
+			count:   2,
+			sum:     2.12,
+			buckets: map[float64]uint64{0.1: 0, 0.25: 1, 1: 1, 2.5: 2, 30: 2},
+
+			exemplarBound: 2.5,
+			exemplarValue: 2,
+			exemplarTime:  300,
+		},
+		{
+			name:    "checks counted once",
+			fetches: [][]check{{{100, 80}}, {{200, 40}, {100, 80}}, {{300, 700}, {200, 40}}},
+			count:   2,
+			sum:     0.74,
+			buckets: map[float64]uint64{0.05: 1, 0.5: 1, 1: 2},
+
+			exemplarBound: 1,
+			exemplarValue: 0.7,
+			exemplarTime:  300,
+		},
+		{
+			name:    "milliseconds",
+			legacy:  true,
+			fetches: [][]check{{{100, 80}}, {{200, 120}, {100, 80}}},
+			count:   1,
+			sum:     120,
+			buckets: map[float64]uint64{100: 0, 250: 1},
+
+			exemplarBound: 250,
+			exemplarValue: 120,
+			exemplarTime:  200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := uptimerobot.New("key", uptimerobot.Options{Logger: zerolog.Nop()})
+			c := New(context.Background(), client, zerolog.Nop(), Options{
+				ResponseTimeHistogram:   true,
+				LegacyResponseTimeUnits: tt.legacy,
+			})
+			var m uptimerobot.Monitor
+			for _, checks := range tt.fetches {
+				m = monitorWithChecks(t, checks)
+				c.observeResponseTimes(map[int]uptimerobot.Monitor{m.ID: m})
+			}
+
+			ch := make(chan prometheus.Metric, 1)
+			c.collectResponseTimeHistogram(ch, m)
+			var out dto.Metric
+			if err := (<-ch).Write(&out); err != nil {
+				t.Fatal(err)
+			}
+
+			h := out.GetHistogram()
+			if h.GetSampleCount() != tt.count {
+				t.Errorf("got a count of %d, want %d", h.GetSampleCount(), tt.count)
+			}
+			if diff := h.GetSampleSum() - tt.sum; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("got a sum of %g, want %g", h.GetSampleSum(), tt.sum)
+			}
+			var (
+				exemplar *dto.Exemplar
+				checked  int
+			)
+			for _, b := range h.GetBucket() {
+				if want, ok := tt.buckets[b.GetUpperBound()]; ok {
+					checked++
+					if b.GetCumulativeCount() != want {
+						t.Errorf("got a count of %d for bucket %g, want %d", b.GetCumulativeCount(), b.GetUpperBound(), want)
+					}
+				}
+				if b.Exemplar == nil {
+					continue
+				}
+				if exemplar != nil {
+					t.Errorf("got several exemplars")
+				}
+				exemplar = b.Exemplar
+				if b.GetUpperBound() != tt.exemplarBound {
+					t.Errorf("got the exemplar on bucket %g, want %g", b.GetUpperBound(), tt.exemplarBound)
+				}
+			}
+
+			if checked != len(tt.buckets) {
+				t.Errorf("got %d of the %d buckets checked", checked, len(tt.buckets))
+			}
+
+			if tt.exemplarBound == 0 {
+				if exemplar != nil {
+					t.Errorf("got exemplar %v, want none", exemplar)
+				}
+				return
+			}
+			if exemplar == nil {
+				t.Fatal("got no exemplar")
+			}
+			if exemplar.GetValue() != tt.exemplarValue {
+				t.Errorf("got an exemplar value of %g, want %g", exemplar.GetValue(), tt.exemplarValue)
+			}
+			if got := exemplar.GetTimestamp().AsTime().Unix(); got != tt.exemplarTime {
+				t.Errorf("got an exemplar at %d, want %d", got, tt.exemplarTime)
+			}
+			labels := exemplar.GetLabel()
+			if len(labels) != 1 || labels[0].GetName() != "monitor_id" || labels[0].GetValue() != "1" {
+				t.Errorf("got exemplar labels %v, want monitor_id=1", labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/common/model"
+)
+
+// reservedLabels are the labels of the per-monitor metrics, which cannot be
+// extracted from the friendly names
+var reservedLabels = map[string]bool{
+	"monitor_id": true, "url": true, "friendly_name": true, "type": true,
+	"sub_type": true, "port": true, "keyword_type": true, "interval": true,
+	"tag": true, "state": true, "quantile": true, "brand": true,
+	"product": true, "window": true, "from": true, "to": true,
+	"keyword_value": true, "le": true,
+}
+
+// nameLabelNames returns the names of the labels extracted from the friendly
+// names by the rules: the named capture groups, in order of appearance
+func nameLabelNames(rules []*regexp.Regexp) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		for _, name := range rule.SubexpNames() {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// CheckNameLabels makes sure that the rules extracting labels from the
+// friendly names have named capture groups that are valid label names and do
+// not collide with the labels of the per-monitor metrics
+func CheckNameLabels(rules []*regexp.Regexp) error {
+	for _, rule := range rules {
+		named := false
+		for _, name := range rule.SubexpNames()[1:] {
+			if name == "" {
+				continue
+			}
+			named = true
+			if !model.LabelName(name).IsValid() {
+				return fmt.Errorf("%s: invalid label name %q", rule, name)
+			}
+			if reservedLabels[name] {
+				return fmt.Errorf("%s: label %q is already used by the exporter", rule, name)
+			}
+		}
+		if !named {
+			return fmt.Errorf("%s: no named capture group", rule)
+		}
+	}
+	return nil
+}
+
+// extractNameLabels returns the values of the labels named names extracted
+// from a friendly name. A label captured by several rules takes the value of
+// the first rule matching, and is empty if none does.
+func extractNameLabels(rules []*regexp.Regexp, names []string, friendlyName string) []string {
+	values := make([]string, len(names))
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+	for _, rule := range rules {
+		match := rule.FindStringSubmatch(friendlyName)
+		if match == nil {
+			continue
+		}
+		for j, name := range rule.SubexpNames() {
+			if i, ok := index[name]; ok && name != "" && values[i] == "" {
+				values[i] = match[j]
+			}
+		}
+	}
+	return values
+}
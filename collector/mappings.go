@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/common/model"
+)
+
+// LabelMapping attaches labels to the monitor with the given ID, or to the
+// monitors whose URL matches the given regular expression
+type LabelMapping struct {
+	ID     int
+	URL    *regexp.Regexp
+	Labels map[string]string
+}
+
+// matches reports whether the mapping applies to the monitor m
+func (lm LabelMapping) matches(m uptimerobot.Monitor) bool {
+	if lm.URL != nil {
+		return lm.URL.MatchString(m.URL)
+	}
+	return lm.ID == m.ID
+}
+
+// LabelMappings holds the label mappings of the collectors, which can be
+// replaced at runtime. The names of the labels are taken when the collectors
+// are created, so the labels added afterwards are ignored.
+type LabelMappings struct {
+	mu       sync.RWMutex
+	mappings []LabelMapping
+}
+
+// NewLabelMappings returns label mappings holding the given mappings, which
+// must be checked with CheckLabelMappings
+func NewLabelMappings(mappings []LabelMapping) *LabelMappings {
+	return &LabelMappings{mappings: mappings}
+}
+
+// Set replaces the mappings, which must be checked with CheckLabelMappings.
+// The new values are exported from the next fetch of the monitors.
+func (l *LabelMappings) Set(mappings []LabelMapping) {
+	l.mu.Lock()
+	l.mappings = mappings
+	l.mu.Unlock()
+}
+
+// Names returns the sorted names of the labels set by the mappings
+func (l *LabelMappings) Names() []string {
+	if l == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return mappedLabelNames(l.mappings)
+}
+
+// apply sets the values of the labels named names set by the mappings
+// matching the monitor m. A label set by several mappings takes the value of
+// the last one.
+func (l *LabelMappings) apply(names, values []string, m uptimerobot.Monitor) {
+	if l == nil {
+		return
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, mapping := range l.mappings {
+		if !mapping.matches(m) {
+			continue
+		}
+		for i, name := range names {
+			if value, ok := mapping.Labels[name]; ok {
+				values[i] = value
+			}
+		}
+	}
+}
+
+// mappedLabelNames returns the sorted names of the labels set by mappings
+func mappedLabelNames(mappings []LabelMapping) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, mapping := range mappings {
+		for name := range mapping.Labels {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckLabelMappings makes sure that the labels set by the mappings are valid
+// label names that do not collide with the labels of the per-monitor metrics,
+// including the ones extracted from the friendly names by nameRules
+func CheckLabelMappings(mappings []LabelMapping, nameRules []*regexp.Regexp) error {
+	extracted := make(map[string]bool)
+	for _, name := range nameLabelNames(nameRules) {
+		extracted[name] = true
+	}
+	for _, name := range mappedLabelNames(mappings) {
+		switch {
+		case !model.LabelName(name).IsValid():
+			return fmt.Errorf("invalid label name %q", name)
+		case reservedLabels[name]:
+			return fmt.Errorf("label %q is already used by the exporter", name)
+		case extracted[name]:
+			return fmt.Errorf("label %q is already extracted from the friendly names", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,762 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// monitorLabels identify the monitor of the per-monitor metrics. The other
+// attributes of the monitors are labels of uptimerobot_monitor_info only, so
+// that changing them does not create new series.
+var monitorLabels = []string{"monitor_id"}
+
+// monitorMetric is a per-monitor metric, labelled with attributes of the
+// monitors, then with its own labels and finally with the labels extracted
+// from the friendly names
+type monitorMetric struct {
+	// name is the name of the metric without the metrics prefix
+	name  string
+	desc  *prometheus.Desc
+	attrs []string
+}
+
+// monitorMetrics holds the per-monitor metrics, whose labels depend on the
+// collector options
+type monitorMetrics struct {
+	info                monitorMetric
+	status              monitorMetric
+	responseTime        monitorMetric
+	responseTimeAverage monitorMetric
+	tagInfo             monitorMetric
+	state               monitorMetric
+	interval            monitorMetric
+	lastCheck           monitorMetric
+	responseTimeRolling monitorMetric
+	responseTimeChecks  monitorMetric
+	sslExpiry           monitorMetric
+	sslInfo             monitorMetric
+	uptimeRatio         monitorMetric
+	downtime            monitorMetric
+	allTimeUptimeRatio  monitorMetric
+	allTimeDuration     monitorMetric
+	downEvents          monitorMetric
+	transitions         monitorMetric
+	heartbeatUp         monitorMetric
+	heartbeatLastPing   monitorMetric
+	keywordType         monitorMetric
+	keywordInfo         monitorMetric
+
+	// responseTimeUnit is the number of milliseconds, the unit of the API, in
+	// the unit of the response time metrics
+	responseTimeUnit float64
+}
+
+// newMonitorMetrics creates the per-monitor metrics, extraLabels being the
+// names of the labels extracted from the friendly names. opts.MetricsPrefix
+// must be set.
+func newMonitorMetrics(opts Options, extraLabels []string) monitorMetrics {
+	newMetric := func(name, help string, attrs []string, labels ...string) monitorMetric {
+		names := append(append(append([]string{}, attrs...), labels...), extraLabels...)
+		return monitorMetric{
+			name:  name,
+			desc:  prometheus.NewDesc(opts.MetricsPrefix+name, help, names, nil),
+			attrs: attrs,
+		}
+	}
+
+	attrs := monitorLabels
+	statusAttrs := []string{"monitor_id", "url", "friendly_name", "interval"}
+	responseTimeAttrs := []string{"monitor_id", "url", "friendly_name", "type"}
+	if opts.Labels != nil {
+		attrs, statusAttrs, responseTimeAttrs = opts.Labels, opts.Labels, opts.Labels
+	} else {
+		// the interval and type are left to uptimerobot_monitor_info and
+		// uptimerobot_monitor_interval_seconds
+		if opts.LowChurnLabels {
+			statusAttrs = statusAttrs[:3]
+			responseTimeAttrs = responseTimeAttrs[:3]
+		}
+		if opts.LegacyMonitorLabels {
+			statusAttrs = statusAttrs[1:]
+			responseTimeAttrs = responseTimeAttrs[1:]
+		}
+	}
+
+	suffix, unit, ms := "_seconds", "seconds", 1000.0
+	if opts.LegacyResponseTimeUnits {
+		suffix, unit, ms = "", "milliseconds", 1
+	}
+
+	return monitorMetrics{
+		responseTimeUnit: ms,
+		info: newMetric(
+			"monitor_info",
+			"Attributes of the monitor",
+			[]string{"monitor_id", "url", "friendly_name", "type", "sub_type", "port", "keyword_type"},
+		),
+		status: newMetric(
+			"monitors_status",
+			"Status of the monitors",
+			statusAttrs,
+		),
+		responseTime: newMetric(
+			"response_time"+suffix,
+			"Latest response time of the monitor, in "+unit,
+			responseTimeAttrs,
+		),
+		responseTimeAverage: newMetric(
+			"response_time_average"+suffix,
+			"Average response time of the monitor, in "+unit,
+			attrs,
+		),
+		tagInfo: newMetric(
+			"monitor_tag_info",
+			"Tag set on the monitor in Uptime Robot",
+			attrs, "tag",
+		),
+		state: newMetric(
+			"monitor_state",
+			"Whether the monitor is in the state given by the state label (1) or not (0)",
+			attrs, "state",
+		),
+		interval: newMetric(
+			"monitor_interval_seconds",
+			"Check interval of the monitor",
+			attrs,
+		),
+		lastCheck: newMetric(
+			"monitor_last_check_timestamp_seconds",
+			"Date of the latest check of the monitor with a response time, as a Unix timestamp",
+			attrs,
+		),
+		responseTimeRolling: newMetric(
+			"response_time_rolling"+suffix,
+			"Quantiles of the response times of the monitor over the rolling window, in "+unit+" (quantile 0 is the minimum and 1 the maximum)",
+			attrs, "quantile",
+		),
+		responseTimeChecks: newMetric(
+			"check_response_time"+suffix,
+			"Response times of the checks of the monitor seen since the exporter started, in "+unit,
+			attrs,
+		),
+		sslExpiry: newMetric(
+			"monitor_ssl_expiry_timestamp_seconds",
+			"Expiry date of the SSL certificate checked by the monitor, as a Unix timestamp",
+			attrs,
+		),
+		sslInfo: newMetric(
+			"monitor_ssl_info",
+			"Issuer of the SSL certificate checked by the monitor",
+			attrs, "brand", "product",
+		),
+		uptimeRatio: newMetric(
+			"monitor_uptime_ratio",
+			"Uptime ratio (0-1) of the monitor over the period given by the window label",
+			attrs, "window",
+		),
+		downtime: newMetric(
+			"monitor_downtime_seconds",
+			"Time the monitor was down over the period given by the window label",
+			attrs, "window",
+		),
+		allTimeUptimeRatio: newMetric(
+			"monitor_all_time_uptime_ratio",
+			"Uptime ratio (0-1) of the monitor since its creation",
+			attrs,
+		),
+		allTimeDuration: newMetric(
+			"monitor_all_time_duration_seconds",
+			"Time spent by the monitor in the state given by the state label (up, down or paused) since its creation",
+			attrs, "state",
+		),
+		downEvents: newMetric(
+			"monitor_down_events_total",
+			"Number of times the monitor went down since the exporter started",
+			attrs,
+		),
+		transitions: newMetric(
+			"monitor_status_transitions_total",
+			"Number of status changes of the monitor seen since the exporter started",
+			attrs, "from", "to",
+		),
+		heartbeatUp: newMetric(
+			"heartbeat_up",
+			"Whether the heartbeat monitor receives its pings (1) or not (0)",
+			attrs,
+		),
+		heartbeatLastPing: newMetric(
+			"heartbeat_last_ping_timestamp_seconds",
+			"Date of the latest ping of the heartbeat monitor known from the API, as a Unix timestamp",
+			attrs,
+		),
+		keywordType: newMetric(
+			"monitor_keyword_type",
+			"Keyword check of a keyword monitor (1: the keyword must exist, 2: it must not exist)",
+			attrs,
+		),
+		keywordInfo: newMetric(
+			"monitor_keyword_info",
+			"Keyword checked by a keyword monitor",
+			attrs, "keyword_type", "keyword_value",
+		),
+	}
+}
+
+// describe sends the descriptions of the per-monitor metrics
+func (mm *monitorMetrics) describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range mm.all() {
+		ch <- metric.desc
+	}
+}
+
+// all returns the per-monitor metrics
+func (mm *monitorMetrics) all() []monitorMetric {
+	return []monitorMetric{
+		mm.info, mm.status, mm.responseTime, mm.responseTimeAverage, mm.tagInfo,
+		mm.state, mm.interval, mm.lastCheck, mm.responseTimeRolling, mm.responseTimeChecks, mm.sslExpiry,
+		mm.sslInfo, mm.uptimeRatio, mm.downtime, mm.allTimeUptimeRatio,
+		mm.allTimeDuration, mm.downEvents, mm.transitions, mm.heartbeatUp,
+		mm.heartbeatLastPing, mm.keywordType, mm.keywordInfo,
+	}
+}
+
+// monitorAttribute returns the value of the attribute of m used as the given
+// label
+func monitorAttribute(m uptimerobot.Monitor, label string) string {
+	switch label {
+	case "monitor_id":
+		return strconv.Itoa(m.ID)
+	case "url":
+		return m.URL
+	case "friendly_name":
+		return m.FriendlyName
+	case "type":
+		return strconv.Itoa(m.Type)
+	case "sub_type":
+		return subTypeName(m)
+	case "port":
+		return monitorPort(m)
+	case "keyword_type":
+		return strconv.Itoa(m.KeywordType)
+	case "interval":
+		return strconv.Itoa(m.Interval)
+	default:
+		return ""
+	}
+}
+
+// portService is the service checked by a port monitor of a given sub type
+type portService struct {
+	name string
+	port string
+}
+
+// portServices are the services of the port monitor sub types, along with
+// their well-known port
+var portServices = map[int]portService{
+	uptimerobot.PortSubTypeHTTP:   {"http", "80"},
+	uptimerobot.PortSubTypeHTTPS:  {"https", "443"},
+	uptimerobot.PortSubTypeFTP:    {"ftp", "21"},
+	uptimerobot.PortSubTypeSMTP:   {"smtp", "25"},
+	uptimerobot.PortSubTypePOP3:   {"pop3", "110"},
+	uptimerobot.PortSubTypeIMAP:   {"imap", "143"},
+	uptimerobot.PortSubTypeCustom: {"custom", ""},
+}
+
+// portServiceOf returns the service checked by the port monitor m
+func portServiceOf(m uptimerobot.Monitor) (portService, bool) {
+	if m.Type != uptimerobot.MonitorTypePort {
+		return portService{}, false
+	}
+	subType, err := strconv.Atoi(m.SubType)
+	if err != nil {
+		return portService{}, false
+	}
+	service, ok := portServices[subType]
+	return service, ok
+}
+
+// subTypeName returns the name of the service checked by a port monitor, or
+// its raw sub type if unknown
+func subTypeName(m uptimerobot.Monitor) string {
+	if service, ok := portServiceOf(m); ok {
+		return service.name
+	}
+	return m.SubType
+}
+
+// monitorPort returns the port checked by a port monitor. The API leaves it
+// empty for the predefined services, which use their well-known port.
+func monitorPort(m uptimerobot.Monitor) string {
+	if m.Port != "" && m.Port != "0" {
+		return m.Port
+	}
+	if service, ok := portServiceOf(m); ok {
+		return service.port
+	}
+	return m.Port
+}
+
+// newMonitorMetric returns a sample of the metric for the monitor m, given
+// the values of the metric's own labels. It must be called with c.mu held.
+func (c *Collector) newMonitorMetric(metric monitorMetric, valueType prometheus.ValueType, value float64, m uptimerobot.Monitor, labelValues ...string) prometheus.Metric {
+	return prometheus.MustNewConstMetric(metric.desc, valueType, value, c.monitorLabelValues(metric, m, labelValues...)...)
+}
+
+// monitorLabelValues returns the label values of the metric for the monitor
+// m, given the values of the metric's own labels. It must be called with c.mu
+// held.
+func (c *Collector) monitorLabelValues(metric monitorMetric, m uptimerobot.Monitor, labelValues ...string) []string {
+	values := make([]string, 0, len(metric.attrs)+len(labelValues)+len(c.nameLabels))
+	for _, attr := range metric.attrs {
+		values = append(values, monitorAttribute(m, attr))
+	}
+	values = append(values, labelValues...)
+	extracted := c.extractedLabels[m.ID]
+	if extracted == nil {
+		extracted = make([]string, len(c.nameLabels))
+	}
+	return append(values, extracted...)
+}
+
+// statuses of the monitors given special treatment
+const (
+	monitorStatusPaused = 0
+	monitorStatusUp     = 2
+)
+
+// monitorStatuses names the statuses of the monitors
+var monitorStatuses = map[int]string{
+	monitorStatusPaused: "paused",
+	1:                   "not_checked",
+	monitorStatusUp:     "up",
+	8:                   "seems_down",
+	9:                   "down",
+}
+
+// ParseMonitorStatuses returns the monitor statuses listed by names, as
+// accepted by Options.MonitorStatuses: paused, not_checked, up, seems_down and
+// down
+func ParseMonitorStatuses(names []string) ([]int, error) {
+	byName := make(map[string]int, len(monitorStatuses))
+	for status, name := range monitorStatuses {
+		byName[name] = status
+	}
+	statuses := make([]int, 0, len(names))
+	seen := make(map[int]bool, len(names))
+	for _, name := range names {
+		status, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown monitor status %q", name)
+		}
+		if !seen[status] {
+			seen[status] = true
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// monitorTypes maps the names accepted by ParseMonitorTypes to monitor types
+var monitorTypes = map[string]int{
+	"http":      uptimerobot.MonitorTypeHTTP,
+	"keyword":   uptimerobot.MonitorTypeKeyword,
+	"ping":      uptimerobot.MonitorTypePing,
+	"port":      uptimerobot.MonitorTypePort,
+	"heartbeat": uptimerobot.MonitorTypeHeartbeat,
+}
+
+// ParseMonitorTypes returns the monitor types listed by names, as accepted by
+// Options.MonitorTypes: http, keyword, ping, port and heartbeat
+func ParseMonitorTypes(names []string) ([]int, error) {
+	types := make([]int, 0, len(names))
+	seen := make(map[int]bool, len(names))
+	for _, name := range names {
+		typ, ok := monitorTypes[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown monitor type %q", name)
+		}
+		if !seen[typ] {
+			seen[typ] = true
+			types = append(types, typ)
+		}
+	}
+	return types, nil
+}
+
+// keywordTypes names the keyword types of the keyword monitors
+var keywordTypes = map[int]string{
+	uptimerobot.KeywordTypeExists:    "exists",
+	uptimerobot.KeywordTypeNotExists: "not_exists",
+}
+
+// uptimeWindows are the periods, in days, of the exported uptime ratios
+var uptimeWindows = []int{1, 7, 30, 90}
+
+// monitorsQuery returns the getMonitors parameters requesting the data of the
+// exported metrics, for the monitors selected by filters
+func (c *Collector) monitorsQuery(filters Filters) uptimerobot.MonitorsQuery {
+	return uptimerobot.MonitorsQuery{
+		IDs:                 filters.IDs,
+		Types:               filters.Types,
+		Statuses:            filters.Statuses,
+		Search:              filters.Search,
+		SSL:                 true,
+		CustomUptimeRatios:  uptimeWindows,
+		CustomDownDurations: true,
+		AllTimeUptime:       true,
+		Tags:                true,
+		Logs:                true,
+		LogTypes:            []int{uptimerobot.LogTypeDown, uptimerobot.LogTypeUp},
+		LogsLimit:           eventsLogsLimit,
+	}
+}
+
+// collectMonitors sends the metrics of the monitors. When the monitor ID is
+// not among the labels, monitors sharing the same labels would collide, so
+// only the uptimerobot_monitor_info of the others is sent besides the one
+// with the lowest ID.
+func (c *Collector) collectMonitors(ch chan<- prometheus.Metric) {
+	if !c.idDropped() {
+		for _, m := range c.monitors {
+			if c.exported(m) {
+				c.collectMonitor(ch, m, c.overriddenMetrics(m), false)
+			}
+		}
+		return
+	}
+
+	ids := make([]int, 0, len(c.monitors))
+	for id, m := range c.monitors {
+		if c.exported(m) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		m := c.monitors[id]
+		key := c.seriesKey(c.monitorMetrics.interval, m)
+		if seen[key] {
+			c.logger.Debug().Msgf("monitor %d has the same labels as another one, only exporting its info", id)
+		}
+		c.collectMonitor(ch, m, c.overriddenMetrics(m), seen[key])
+		seen[key] = true
+	}
+}
+
+// exported reports whether the per-monitor metrics of m are exported, paused
+// monitors being left out with Options.SkipPaused
+func (c *Collector) exported(m uptimerobot.Monitor) bool {
+	return !c.opts.SkipPaused || m.Status != monitorStatusPaused
+}
+
+// idDropped reports whether the monitor ID was removed from the labels with
+// Options.Labels
+func (c *Collector) idDropped() bool {
+	if c.opts.Labels == nil {
+		return false
+	}
+	for _, label := range c.opts.Labels {
+		if label == "monitor_id" {
+			return false
+		}
+	}
+	return true
+}
+
+// collectMonitor sends the metrics of a monitor, or only its info when
+// infoOnly is set. When allowed is not nil, as set by the override of the
+// monitor, only the metrics it holds are sent. The monitor ID keeps apart
+// monitors sharing the same name and URL. uptimerobot_monitors_status and the
+// latest response time keep their historical labels.
+func (c *Collector) collectMonitor(ch chan<- prometheus.Metric, m uptimerobot.Monitor, allowed map[*prometheus.Desc]bool, infoOnly bool) {
+	mm := &c.monitorMetrics
+	exports := func(metric monitorMetric) bool {
+		return allowed == nil || allowed[metric.desc]
+	}
+	gauge := func(metric monitorMetric, value float64, labelValues ...string) {
+		if exports(metric) {
+			ch <- c.newMonitorMetric(metric, prometheus.GaugeValue, value, m, labelValues...)
+		}
+	}
+
+	gauge(mm.info, 1)
+	if infoOnly {
+		return
+	}
+	if !c.opts.LegacyMonitorLabels {
+		gauge(mm.status, float64(m.Status))
+	}
+	for _, tag := range m.Tags {
+		gauge(mm.tagInfo, 1, tag.Name)
+	}
+	for status, name := range monitorStatuses {
+		gauge(mm.state, boolToFloat(m.Status == status), name)
+	}
+	gauge(mm.interval, float64(m.Interval))
+
+	// heartbeat monitors wait for pings instead of checking a target, so
+	// they have no response times
+	if m.Type == uptimerobot.MonitorTypeHeartbeat {
+		gauge(mm.heartbeatUp, boolToFloat(m.Status == monitorStatusUp))
+		if last := lastPing(m); last > 0 {
+			gauge(mm.heartbeatLastPing, float64(last))
+		}
+	} else {
+		if len(m.ResponseTimes) > 0 && !c.opts.LegacyMonitorLabels && exports(mm.responseTime) {
+			ch <- c.responseTimeMetric(m)
+		}
+		if last := lastCheck(m); last > 0 {
+			gauge(mm.lastCheck, float64(last))
+		}
+		if average, err := m.AverageResponseTime.Float64(); err == nil {
+			gauge(mm.responseTimeAverage, average/mm.responseTimeUnit)
+		}
+	}
+
+	if m.Type == uptimerobot.MonitorTypeKeyword {
+		keywordType, ok := keywordTypes[m.KeywordType]
+		if !ok {
+			keywordType = strconv.Itoa(m.KeywordType)
+		}
+		gauge(mm.keywordType, float64(m.KeywordType))
+		gauge(mm.keywordInfo, 1, keywordType, m.KeywordValue)
+	}
+
+	if m.SSL != nil && m.SSL.Expires > 0 {
+		gauge(mm.sslExpiry, float64(m.SSL.Expires))
+		gauge(mm.sslInfo, 1, m.SSL.Brand, m.SSL.Product)
+	}
+
+	ratios, err := m.CustomUptimeRatios()
+	if err != nil {
+		c.logger.Warn().Err(err).Msgf("invalid uptime ratios for monitor %d", m.ID)
+	}
+	for i, ratio := range ratios {
+		if i >= len(uptimeWindows) {
+			break
+		}
+		gauge(mm.uptimeRatio, ratio/100, strconv.Itoa(uptimeWindows[i])+"d")
+	}
+
+	downtimes, err := m.DownDurations()
+	if err != nil {
+		c.logger.Warn().Err(err).Msgf("invalid down durations for monitor %d", m.ID)
+	}
+	for i, downtime := range downtimes {
+		if i >= len(uptimeWindows) {
+			break
+		}
+		gauge(mm.downtime, downtime, strconv.Itoa(uptimeWindows[i])+"d")
+	}
+
+	if ratio, err := strconv.ParseFloat(m.AllTimeUptimeRatio, 64); err == nil {
+		gauge(mm.allTimeUptimeRatio, ratio/100)
+	}
+	durations, err := m.AllTimeDurations()
+	if err != nil {
+		c.logger.Warn().Err(err).Msgf("invalid all-time durations for monitor %d", m.ID)
+	}
+	if durations != nil {
+		gauge(mm.allTimeDuration, durations.Up, "up")
+		gauge(mm.allTimeDuration, durations.Down, "down")
+		gauge(mm.allTimeDuration, durations.Paused, "paused")
+	}
+
+	if counter, ok := c.downEvents[m.ID]; ok && exports(mm.downEvents) {
+		ch <- c.newMonitorMetric(mm.downEvents, prometheus.CounterValue, counter.count, m)
+	}
+	if history, ok := c.statuses[m.ID]; ok && exports(mm.transitions) {
+		for t, count := range history.transitions {
+			ch <- c.newMonitorMetric(mm.transitions, prometheus.CounterValue, count, m, statusName(t.from), statusName(t.to))
+		}
+	}
+	if exports(mm.responseTimeRolling) {
+		c.collectRollingResponseTime(ch, m)
+	}
+	if exports(mm.responseTimeChecks) {
+		c.collectResponseTimeHistogram(ch, m)
+	}
+}
+
+// collectLegacyMonitors sends uptimerobot_monitors_status and the latest
+// response time without monitor_id. Monitors sharing the same labels would
+// collide, so only the one with the lowest ID is kept.
+func (c *Collector) collectLegacyMonitors(ch chan<- prometheus.Metric, monitors map[int]uptimerobot.Monitor) {
+	ids := make([]int, 0, len(monitors))
+	for id, m := range monitors {
+		if c.exported(m) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	seenStatus := make(map[string]bool, len(ids))
+	seenResponseTime := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		m := monitors[id]
+		allowed := c.overriddenMetrics(m)
+		status := c.newMonitorMetric(c.monitorMetrics.status, prometheus.GaugeValue, float64(m.Status), m)
+		if key := c.seriesKey(c.monitorMetrics.status, m); seenStatus[key] {
+			c.logger.Debug().Msgf("monitor %d has the same labels as another one, skipping its status", id)
+		} else if allowed == nil || allowed[status.Desc()] {
+			seenStatus[key] = true
+			ch <- status
+		}
+
+		if len(m.ResponseTimes) == 0 || m.Type == uptimerobot.MonitorTypeHeartbeat {
+			continue
+		}
+		if allowed != nil && !allowed[c.monitorMetrics.responseTime.desc] {
+			continue
+		}
+		if key := c.seriesKey(c.monitorMetrics.responseTime, m); !seenResponseTime[key] {
+			seenResponseTime[key] = true
+			ch <- c.responseTimeMetric(m)
+		}
+	}
+}
+
+// responseTimeMetric returns the latest response time of the monitor m, which
+// must have one, timestamped with the date of the check if
+// Options.ResponseTimeTimestamps is set
+func (c *Collector) responseTimeMetric(m uptimerobot.Monitor) prometheus.Metric {
+	rt := m.ResponseTimes[0]
+	metric := c.newMonitorMetric(c.monitorMetrics.responseTime, prometheus.GaugeValue, float64(rt.Value)/c.monitorMetrics.responseTimeUnit, m)
+	if c.opts.ResponseTimeTimestamps && rt.Datetime > 0 {
+		metric = prometheus.NewMetricWithTimestamp(time.Unix(int64(rt.Datetime), 0), metric)
+	}
+	return metric
+}
+
+// seriesKey identifies the series of a metric without labels of its own for
+// the monitor m
+func (c *Collector) seriesKey(metric monitorMetric, m uptimerobot.Monitor) string {
+	values := make([]string, 0, len(metric.attrs)+len(c.nameLabels))
+	for _, attr := range metric.attrs {
+		values = append(values, monitorAttribute(m, attr))
+	}
+	values = append(values, c.extractedLabels[m.ID]...)
+	return strings.Join(values, "\xff")
+}
+
+// collectMonitorsByStatus sends the number of monitors in each status
+func (c *Collector) collectMonitorsByStatus(ch chan<- prometheus.Metric, monitors map[int]uptimerobot.Monitor) {
+	counts := make(map[string]int, len(monitorStatuses))
+	for _, name := range monitorStatuses {
+		counts[name] = 0
+	}
+	for _, m := range monitors {
+		counts[statusName(m.Status)]++
+	}
+	for name, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.monitorsByStatusDesc, prometheus.GaugeValue, float64(count), name)
+	}
+}
+
+// statusName returns the name of a monitor status
+func statusName(status int) string {
+	if name, ok := monitorStatuses[status]; ok {
+		return name
+	}
+	return strconv.Itoa(status)
+}
+
+// lastCheck returns the date of the latest response time of a monitor, 0 if
+// it has none
+func lastCheck(m uptimerobot.Monitor) int {
+	last := 0
+	for _, rt := range m.ResponseTimes {
+		if rt.Datetime > last {
+			last = rt.Datetime
+		}
+	}
+	return last
+}
+
+// lastPing returns the date of the latest ping of a heartbeat monitor known
+// from the API: its latest response time if any, or else the date it was
+// last logged up. It returns 0 if there is none.
+func lastPing(m uptimerobot.Monitor) int {
+	if last := lastCheck(m); last > 0 {
+		return last
+	}
+	last := 0
+	for _, l := range m.Logs {
+		if l.Type == uptimerobot.LogTypeUp && l.Datetime > last {
+			last = l.Datetime
+		}
+	}
+	return last
+}
+
+// recordResponseTimes adds the latest response time of the fetched monitors
+// to their rolling window. It must be called with c.mu held.
+func (c *Collector) recordResponseTimes(monitors map[int]uptimerobot.Monitor) {
+	if c.opts.ResponseTimeWindow <= 0 {
+		return
+	}
+	windows := make(map[int]*samples, len(monitors))
+	for id, m := range monitors {
+		window, ok := c.responseTimes[id]
+		if !ok {
+			window = newSamples(c.opts.ResponseTimeWindow, m.Interval)
+		}
+		for i := len(m.ResponseTimes) - 1; i >= 0; i-- {
+			rt := m.ResponseTimes[i]
+			window.add(sample{datetime: rt.Datetime, value: float64(rt.Value)})
+		}
+		windows[id] = window
+	}
+	c.responseTimes = windows
+}
+
+// collectRollingResponseTime sends the response time quantiles of a monitor
+// over the rolling window
+func (c *Collector) collectRollingResponseTime(ch chan<- prometheus.Metric, m uptimerobot.Monitor) {
+	window, ok := c.responseTimes[m.ID]
+	if !ok {
+		return
+	}
+	since := int(time.Now().Add(-c.opts.ResponseTimeWindow).Unix())
+	for i, value := range window.quantiles(since) {
+		ch <- c.newMonitorMetric(c.monitorMetrics.responseTimeRolling, prometheus.GaugeValue, value/c.monitorMetrics.responseTimeUnit, m,
+			strconv.FormatFloat(rollingQuantiles[i], 'g', -1, 64))
+	}
+}
+
+// attributeLabels maps the names accepted by ParseLabels to label names
+var attributeLabels = map[string]string{
+	"id":            "monitor_id",
+	"url":           "url",
+	"friendly_name": "friendly_name",
+	"type":          "type",
+	"interval":      "interval",
+	"port":          "port",
+}
+
+// ParseLabels returns the label names of the monitor attributes listed by
+// names, as accepted by Options.Labels: id, url, friendly_name, type,
+// interval and port
+func ParseLabels(names []string) ([]string, error) {
+	labels := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		label, ok := attributeLabels[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown monitor attribute %q", name)
+		}
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"strconv"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// mwindowLabels identify the maintenance window of the maintenance window
+// metrics
+var mwindowLabels = []string{"mwindow_id", "friendly_name", "type"}
+
+// mwindowMetrics are the metrics of the maintenance windows
+type mwindowMetrics struct {
+	status         *prometheus.Desc
+	startTimestamp *prometheus.Desc
+	startTimeOfDay *prometheus.Desc
+	duration       *prometheus.Desc
+}
+
+// newMWindowMetrics creates the maintenance window metrics, whose names start
+// with prefix
+func newMWindowMetrics(prefix string) mwindowMetrics {
+	return mwindowMetrics{
+		status: prometheus.NewDesc(
+			prefix+"maintenance_window_status",
+			"Status of the maintenance window (0: paused, 1: active)",
+			mwindowLabels, nil,
+		),
+		startTimestamp: prometheus.NewDesc(
+			prefix+"maintenance_window_start_timestamp_seconds",
+			"Start of a one-time maintenance window, as a Unix timestamp",
+			mwindowLabels, nil,
+		),
+		startTimeOfDay: prometheus.NewDesc(
+			prefix+"maintenance_window_start_time_of_day_seconds",
+			"Start of a recurring maintenance window, in seconds after midnight",
+			mwindowLabels, nil,
+		),
+		duration: prometheus.NewDesc(
+			prefix+"maintenance_window_duration_seconds",
+			"Duration of the maintenance window",
+			mwindowLabels, nil,
+		),
+	}
+}
+
+// describe sends the descriptions of the maintenance window metrics
+func (m *mwindowMetrics) describe(ch chan<- *prometheus.Desc) {
+	ch <- m.status
+	ch <- m.startTimestamp
+	ch <- m.startTimeOfDay
+	ch <- m.duration
+}
+
+// mwindowTypes names the maintenance window types
+var mwindowTypes = map[int]string{
+	uptimerobot.MWindowTypeOnce:    "once",
+	uptimerobot.MWindowTypeDaily:   "daily",
+	uptimerobot.MWindowTypeWeekly:  "weekly",
+	uptimerobot.MWindowTypeMonthly: "monthly",
+}
+
+// collectMWindow sends the metrics of a maintenance window
+func (c *Collector) collectMWindow(ch chan<- prometheus.Metric, w uptimerobot.MWindow) {
+	typ, ok := mwindowTypes[w.Type]
+	if !ok {
+		typ = strconv.Itoa(w.Type)
+	}
+	labels := []string{strconv.Itoa(w.ID), w.FriendlyName, typ}
+
+	ch <- prometheus.MustNewConstMetric(c.mwindowMetrics.status, prometheus.GaugeValue, float64(w.Status), labels...)
+	ch <- prometheus.MustNewConstMetric(c.mwindowMetrics.duration, prometheus.GaugeValue, float64(w.Duration*60), labels...)
+
+	start, err := w.Start()
+	switch {
+	case err != nil:
+		c.logger.Warn().Err(err).Msgf("invalid start time for maintenance window %d", w.ID)
+	case w.Type == uptimerobot.MWindowTypeOnce:
+		ch <- prometheus.MustNewConstMetric(c.mwindowMetrics.startTimestamp, prometheus.GaugeValue, start, labels...)
+	default:
+		ch <- prometheus.MustNewConstMetric(c.mwindowMetrics.startTimeOfDay, prometheus.GaugeValue, start, labels...)
+	}
+}
@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MonitorOverride tunes the export of a single monitor. Its extra labels are
+// set with a LabelMapping.
+type MonitorOverride struct {
+	// Disabled leaves the monitor out of the exported metrics
+	Disabled bool
+	// FriendlyName replaces the friendly name of the monitor when not empty
+	FriendlyName string
+	// Metrics restricts the per-monitor metrics exported for the monitor to
+	// the given ones, named without the metrics prefix, when not empty
+	Metrics []string
+}
+
+// MonitorOverrides holds the overrides of the monitors by ID, which can be
+// replaced at runtime
+type MonitorOverrides struct {
+	mu        sync.RWMutex
+	overrides map[int]MonitorOverride
+}
+
+// NewMonitorOverrides returns monitor overrides holding the given overrides,
+// which must be checked with CheckMonitorOverrides
+func NewMonitorOverrides(overrides map[int]MonitorOverride) *MonitorOverrides {
+	return &MonitorOverrides{overrides: overrides}
+}
+
+// Set replaces the overrides, which must be checked with
+// CheckMonitorOverrides. They are applied from the next fetch of the monitors.
+func (o *MonitorOverrides) Set(overrides map[int]MonitorOverride) {
+	o.mu.Lock()
+	o.overrides = overrides
+	o.mu.Unlock()
+}
+
+// get returns the override of the monitor with the given ID, if any
+func (o *MonitorOverrides) get(id int) (MonitorOverride, bool) {
+	if o == nil {
+		return MonitorOverride{}, false
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	override, ok := o.overrides[id]
+	return override, ok
+}
+
+// CheckMonitorOverrides makes sure that the overrides only name existing
+// per-monitor metrics
+func CheckMonitorOverrides(overrides map[int]MonitorOverride) error {
+	known := make(map[string]bool)
+	for _, legacy := range []bool{false, true} {
+		mm := newMonitorMetrics(Options{LegacyResponseTimeUnits: legacy}, nil)
+		for _, metric := range mm.all() {
+			known[metric.name] = true
+		}
+	}
+	for id, override := range overrides {
+		for _, name := range override.Metrics {
+			if !known[name] {
+				return fmt.Errorf("monitor %d: unknown metric %q", id, name)
+			}
+		}
+	}
+	return nil
+}
+
+// applyOverride returns the monitor m with its override applied, and false if
+// it is disabled
+func (c *Collector) applyOverride(m uptimerobot.Monitor) (uptimerobot.Monitor, bool) {
+	override, ok := c.opts.Overrides.get(m.ID)
+	if !ok {
+		return m, true
+	}
+	if override.FriendlyName != "" {
+		m.FriendlyName = override.FriendlyName
+	}
+	return m, !override.Disabled
+}
+
+// overriddenMetrics returns the descriptions of the per-monitor metrics
+// exported for the monitor m, nil if they all are
+func (c *Collector) overriddenMetrics(m uptimerobot.Monitor) map[*prometheus.Desc]bool {
+	override, ok := c.opts.Overrides.get(m.ID)
+	if !ok || len(override.Metrics) == 0 {
+		return nil
+	}
+	names := make(map[string]bool, len(override.Metrics))
+	for _, name := range override.Metrics {
+		names[name] = true
+	}
+	descs := make(map[*prometheus.Desc]bool, len(names))
+	for _, metric := range c.monitorMetrics.all() {
+		if names[metric.name] {
+			descs[metric.desc] = true
+		}
+	}
+	return descs
+}
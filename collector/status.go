@@ -0,0 +1,66 @@
+package collector
+
+import "time"
+
+// Status is the state of the fetches of a collector
+type Status struct {
+	// Ready is false until the first fetches started by Run are over
+	Ready bool
+	// Failing is true when the monitors cannot be served, because they were
+	// never fetched or were dropped after too many failed fetches
+	Failing bool
+	// Monitors is the number of monitors served
+	Monitors int
+	// Fetches holds the state of the fetches of each kind of data, by name
+	Fetches map[string]FetchStatus
+}
+
+// FetchStatus is the state of the fetches of a kind of data
+type FetchStatus struct {
+	// Success tells whether the last fetch succeeded
+	Success bool
+	// Duration is the duration of the last fetch
+	Duration time.Duration
+	// LastSuccess and LastError are the dates of the last successful and
+	// failed fetches, zero if there is none
+	LastSuccess time.Time
+	LastError   time.Time
+	// Error is the error of the last failed fetch, if any
+	Error string
+	// ConsecutiveFailures is the number of failed fetches since the last
+	// successful one
+	ConsecutiveFailures int
+}
+
+// Status returns the state of the fetches of the collector
+func (c *Collector) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	failures := map[string]int{
+		"account":             c.accountFailures,
+		"monitors":            c.monitorsFailures,
+		"maintenance_windows": c.mwindowsFailures,
+	}
+	status := Status{
+		Ready:    c.Ready(),
+		Monitors: len(c.monitors),
+		Fetches:  make(map[string]FetchStatus, len(c.fetches)),
+	}
+	for name, fetch := range c.fetches {
+		fs := FetchStatus{
+			Success:             fetch.success,
+			Duration:            fetch.duration,
+			LastSuccess:         fetch.lastSuccess,
+			LastError:           fetch.lastError,
+			ConsecutiveFailures: failures[name],
+		}
+		if fetch.lastErr != nil {
+			fs.Error = fetch.lastErr.Error()
+		}
+		status.Fetches[name] = fs
+	}
+	_, fetched := c.fetches["monitors"]
+	status.Failing = fetched && c.monitors == nil
+	return status
+}
@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+)
+
+// MonitorState is the current state of a monitor, as seen by the collector
+type MonitorState struct {
+	ID           int
+	FriendlyName string
+	URL          string
+	// Type is the name of the monitor type, such as http or keyword
+	Type string
+	// Status is the name of the monitor status, such as up or down
+	Status   string
+	Interval time.Duration
+	Tags     []string
+	// ResponseTime is the latest response time, 0 if there is none
+	ResponseTime time.Duration
+	// LastCheck is the date of the latest check with a response time, or of
+	// the latest ping of a heartbeat monitor, zero if there is none
+	LastCheck time.Time
+	// UptimeRatios are the uptime ratios (0-1) of the monitor by period, such
+	// as 7d, and all_time since its creation
+	UptimeRatios map[string]float64
+}
+
+// Monitors returns the state of the exported monitors, sorted by ID. In
+// on-demand mode, they are fetched from the API first.
+func (c *Collector) Monitors(ctx context.Context) ([]MonitorState, error) {
+	if c.opts.OnDemand {
+		if err := c.fetchMonitors(ctx); err != nil {
+			c.mu.RLock()
+			fetched := c.monitors != nil
+			c.mu.RUnlock()
+			if !fetched {
+				return nil, err
+			}
+		}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.monitors == nil {
+		return nil, errors.New("monitors not fetched yet")
+	}
+
+	states := make([]MonitorState, 0, len(c.monitors))
+	for _, m := range c.monitors {
+		if c.exported(m) {
+			states = append(states, c.monitorState(m))
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+	return states, nil
+}
+
+// monitorState returns the state of the monitor m
+func (c *Collector) monitorState(m uptimerobot.Monitor) MonitorState {
+	state := MonitorState{
+		ID:           m.ID,
+		FriendlyName: m.FriendlyName,
+		URL:          m.URL,
+		Type:         typeName(m.Type),
+		Status:       statusName(m.Status),
+		Interval:     time.Duration(m.Interval) * time.Second,
+		UptimeRatios: make(map[string]float64),
+	}
+	for _, tag := range m.Tags {
+		state.Tags = append(state.Tags, tag.Name)
+	}
+
+	last := lastCheck(m)
+	if m.Type == uptimerobot.MonitorTypeHeartbeat {
+		last = lastPing(m)
+	} else if len(m.ResponseTimes) > 0 {
+		state.ResponseTime = time.Duration(m.ResponseTimes[0].Value) * time.Millisecond
+	}
+	if last > 0 {
+		state.LastCheck = time.Unix(int64(last), 0)
+	}
+
+	// invalid ratios are already logged when collecting the metrics
+	ratios, _ := m.CustomUptimeRatios()
+	for i, ratio := range ratios {
+		if i >= len(uptimeWindows) {
+			break
+		}
+		state.UptimeRatios[strconv.Itoa(uptimeWindows[i])+"d"] = ratio / 100
+	}
+	if ratio, err := strconv.ParseFloat(m.AllTimeUptimeRatio, 64); err == nil {
+		state.UptimeRatios["all_time"] = ratio / 100
+	}
+	return state
+}
+
+// typeName returns the name of a monitor type, as accepted by
+// ParseMonitorTypes, or its number if unknown
+func typeName(monitorType int) string {
+	for name, t := range monitorTypes {
+		if t == monitorType {
+			return name
+		}
+	}
+	return strconv.Itoa(monitorType)
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// maxWindowSamples bounds the number of response time samples kept per
+// monitor
+const maxWindowSamples = 4096
+
+// rollingQuantiles are the quantiles of the rolling response time metric, 0
+// and 1 being the minimum and the maximum
+var rollingQuantiles = []float64{0, 0.5, 0.95, 0.99, 1}
+
+// sample is a response time measured by Uptime Robot
+type sample struct {
+	datetime int
+	value    float64
+}
+
+// samples is a ring buffer of the latest response time samples of a monitor
+type samples struct {
+	buf   []sample
+	start int
+	n     int
+}
+
+// newSamples creates a ring buffer holding enough samples of a monitor
+// checked every interval seconds to cover window
+func newSamples(window time.Duration, interval int) *samples {
+	size := maxWindowSamples
+	if interval > 0 {
+		size = int(window/(time.Duration(interval)*time.Second)) + 1
+	}
+	if size > maxWindowSamples {
+		size = maxWindowSamples
+	}
+	return &samples{buf: make([]sample, size)}
+}
+
+// add appends s, overwriting the oldest sample when the buffer is full.
+// Samples not newer than the latest one are ignored, so that a sample
+// returned by several polls is only counted once.
+func (r *samples) add(s sample) {
+	if r.n > 0 && s.datetime <= r.buf[(r.start+r.n-1)%len(r.buf)].datetime {
+		return
+	}
+	if r.n < len(r.buf) {
+		r.buf[(r.start+r.n)%len(r.buf)] = s
+		r.n++
+		return
+	}
+	r.buf[r.start] = s
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+// quantiles returns the rollingQuantiles of the samples taken after since,
+// nil if there is none
+func (r *samples) quantiles(since int) []float64 {
+	values := make([]float64, 0, r.n)
+	for i := 0; i < r.n; i++ {
+		if s := r.buf[(r.start+i)%len(r.buf)]; s.datetime > since {
+			values = append(values, s.value)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Float64s(values)
+
+	result := make([]float64, len(rollingQuantiles))
+	for i, q := range rollingQuantiles {
+		// nearest-rank method
+		rank := int(math.Ceil(q * float64(len(values))))
+		if rank > 0 {
+			rank--
+		}
+		result[i] = values[rank]
+	}
+	return result
+}
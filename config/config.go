@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the content of the exporter configuration file. Its settings are
+// overridden by the matching command line flags.
+type Config struct {
+	APIKey           string        `yaml:"api_key"`
+	Interval         time.Duration `yaml:"interval"`
+	AccountInterval  time.Duration `yaml:"account_interval"`
+	MonitorsInterval time.Duration `yaml:"monitors_interval"`
+	Jitter           time.Duration `yaml:"jitter"`
+	LogLevel         string        `yaml:"log_level"`
+	Accounts         []Account     `yaml:"accounts"`
+	// the filters selecting the exported monitors, as the matching flags
+	MonitorIDs      []int    `yaml:"monitor_ids"`
+	MonitorTypes    []string `yaml:"monitor_types"`
+	MonitorStatuses []string `yaml:"monitor_statuses"`
+	MonitorSearch   string   `yaml:"monitor_search"`
+	Tags            []string `yaml:"tags"`
+	IncludeMonitors string   `yaml:"include_monitors"`
+	ExcludeMonitors string   `yaml:"exclude_monitors"`
+	// FriendlyNameLabels are regular expressions whose named capture groups
+	// become labels of the per-monitor metrics
+	FriendlyNameLabels []string `yaml:"friendly_name_labels"`
+	// Labels are the monitor attributes labelling the per-monitor metrics
+	Labels []string `yaml:"labels"`
+	// Monitors tune the export of single monitors, by ID
+	Monitors map[int]MonitorOverride `yaml:"monitors"`
+	// BasicAuthUsers are the users allowed to query the exporter, with the
+	// bcrypt hashes of their passwords
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+}
+
+// MonitorOverride tunes the export of a single monitor
+type MonitorOverride struct {
+	// Disabled leaves the monitor out of the exported metrics
+	Disabled bool `yaml:"disabled"`
+	// FriendlyName replaces the friendly name of the monitor
+	FriendlyName string `yaml:"friendly_name"`
+	// Labels are extra labels of the per-monitor metrics of the monitor
+	Labels map[string]string `yaml:"labels"`
+	// Metrics restricts the per-monitor metrics exported for the monitor,
+	// named without the uptimerobot_ prefix
+	Metrics []string `yaml:"metrics"`
+}
+
+// Account is an Uptime Robot account that can be scraped through the /probe
+// endpoint
+type Account struct {
+	Name   string `yaml:"name"`
+	APIKey string `yaml:"api_key"`
+}
+
+// Load reads and validates the configuration file located at path
+func Load(path string) (*Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalStrict(content, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	for name, interval := range map[string]time.Duration{
+		"interval":          c.Interval,
+		"account_interval":  c.AccountInterval,
+		"monitors_interval": c.MonitorsInterval,
+		"jitter":            c.Jitter,
+	} {
+		if interval < 0 {
+			return fmt.Errorf("%s: must be positive, got %s", name, interval)
+		}
+	}
+
+	for _, id := range c.MonitorIDs {
+		if id <= 0 {
+			return fmt.Errorf("monitor_ids: invalid monitor ID %d", id)
+		}
+	}
+	for name, expr := range map[string]string{
+		"include_monitors": c.IncludeMonitors,
+		"exclude_monitors": c.ExcludeMonitors,
+	} {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	for i, expr := range c.FriendlyNameLabels {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("friendly_name_labels[%d]: %w", i, err)
+		}
+	}
+
+	for id := range c.Monitors {
+		if id <= 0 {
+			return fmt.Errorf("monitors: invalid monitor ID %d", id)
+		}
+	}
+
+	for user, hash := range c.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("basic_auth_users: password of %s is not a bcrypt hash: %w", user, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, acc := range c.Accounts {
+		if acc.Name == "" {
+			return fmt.Errorf("accounts[%d]: missing name", i)
+		}
+		if seen[acc.Name] {
+			return fmt.Errorf("accounts[%d]: duplicate account name %q", i, acc.Name)
+		}
+		seen[acc.Name] = true
+
+		if acc.APIKey == "" {
+			return fmt.Errorf("accounts[%d]: missing api_key", i)
+		}
+	}
+	return nil
+}
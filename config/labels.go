@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MonitorLabels is the content of the monitor labels file, which attaches
+// labels to the monitors
+type MonitorLabels struct {
+	Monitors []MonitorLabelsEntry `yaml:"monitors"`
+}
+
+// MonitorLabelsEntry sets labels on the monitor with the given ID, or on the
+// monitors whose URL matches the given regular expression
+type MonitorLabelsEntry struct {
+	ID     int               `yaml:"id"`
+	URL    string            `yaml:"url"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+// LoadMonitorLabels reads and validates the monitor labels file located at
+// path
+func LoadMonitorLabels(path string) (*MonitorLabels, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var labels MonitorLabels
+	if err := yaml.UnmarshalStrict(content, &labels); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	if err := labels.validate(); err != nil {
+		return nil, fmt.Errorf("invalid monitor labels %s: %w", path, err)
+	}
+	return &labels, nil
+}
+
+func (l *MonitorLabels) validate() error {
+	for i, entry := range l.Monitors {
+		switch {
+		case entry.ID == 0 && entry.URL == "":
+			return fmt.Errorf("monitors[%d]: missing id or url", i)
+		case entry.ID != 0 && entry.URL != "":
+			return fmt.Errorf("monitors[%d]: id and url are mutually exclusive", i)
+		case len(entry.Labels) == 0:
+			return fmt.Errorf("monitors[%d]: missing labels", i)
+		}
+		if entry.URL != "" {
+			if _, err := regexp.Compile(entry.URL); err != nil {
+				return fmt.Errorf("monitors[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+)
+
+// debugResponse is a raw API answer served by /debug/api
+type debugResponse struct {
+	Method          string          `json:"method"`
+	Params          url.Values      `json:"params"`
+	Time            time.Time       `json:"time"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	StatusCode      int             `json:"status_code"`
+	Error           string          `json:"error,omitempty"`
+	Body            json.RawMessage `json:"body,omitempty"`
+	// RawBody holds the answers that are not valid JSON
+	RawBody string `json:"raw_body,omitempty"`
+}
+
+// debugAPIHandler serves the latest raw answers of the API methods called for
+// the main account, or for the account named by the account query parameter,
+// along with the errors met while decoding them. The secrets are masked.
+func (a *app) debugAPIHandler(w http.ResponseWriter, r *http.Request) {
+	acc := a.lookupAccount(w, r)
+	if acc == nil {
+		return
+	}
+
+	var responses []debugResponse
+	for _, resp := range acc.client.LatestResponses() {
+		responses = append(responses, newDebugResponse(resp))
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(responses); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(logger.Redact(body.String())))
+}
+
+// newDebugResponse returns the debug version of an API answer
+func newDebugResponse(resp uptimerobot.Response) debugResponse {
+	debug := debugResponse{
+		Method:          resp.Method,
+		Params:          resp.Params,
+		Time:            resp.Time,
+		DurationSeconds: resp.Duration.Seconds(),
+		StatusCode:      resp.StatusCode,
+		Error:           resp.Error,
+	}
+	if json.Valid(resp.Body) {
+		debug.Body = resp.Body
+	} else {
+		debug.RawBody = string(resp.Body)
+	}
+	return debug
+}
@@ -3,7 +3,15 @@ module github.com/eze-kiel/uptimerobot-exporter
 go 1.16
 
 require (
+	github.com/aws/aws-sdk-go v1.44.0
+	github.com/fsnotify/fsnotify v1.5.4
 	github.com/prometheus/client_golang v1.11.0
+	github.com/prometheus/client_model v0.2.0
+	github.com/prometheus/common v0.26.0
+	github.com/prometheus/exporter-toolkit v0.5.1
 	github.com/rs/zerolog v1.23.0
 	github.com/sirupsen/logrus v1.8.1
+	golang.org/x/crypto v0.0.0-20201208171446-5f87f3452ae9
+	google.golang.org/protobuf v1.26.0-rc.1
+	gopkg.in/yaml.v2 v2.4.0
 )
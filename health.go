@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+)
+
+// health is the body of /health
+type health struct {
+	// Status is ok, degraded when the last fetch of some data failed while
+	// older data is still served, or failing when the monitors cannot be
+	// served
+	Status    string                 `json:"status"`
+	OnDemand  bool                   `json:"on_demand"`
+	Intervals *healthIntervals       `json:"intervals,omitempty"`
+	Monitors  int                    `json:"monitors"`
+	Fetches   map[string]healthFetch `json:"fetches"`
+}
+
+// healthIntervals are the polling intervals, when not in on-demand mode
+type healthIntervals struct {
+	Account  string `json:"account"`
+	Monitors string `json:"monitors"`
+}
+
+// healthFetch is the state of the fetches of a kind of data
+type healthFetch struct {
+	Success             bool       `json:"success"`
+	DurationSeconds     float64    `json:"duration_seconds"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastError           *time.Time `json:"last_error,omitempty"`
+	Error               string     `json:"error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+// healthHandler describes the state of the fetches of the main account in
+// JSON. It answers 503 when the monitors cannot be served, and 200 otherwise.
+func (a *app) healthHandler(w http.ResponseWriter, r *http.Request) {
+	a.mu.RLock()
+	c := a.collector
+	intervals := a.intervals
+	a.mu.RUnlock()
+
+	body := health{
+		Status:   "ok",
+		OnDemand: a.onDemand,
+		Fetches:  make(map[string]healthFetch),
+	}
+	if !a.onDemand {
+		body.Intervals = &healthIntervals{
+			Account:  intervals.Account.String(),
+			Monitors: intervals.Monitors.String(),
+		}
+	}
+
+	code := http.StatusOK
+	if c != nil {
+		status := c.Status()
+		body.Monitors = status.Monitors
+		for name, fetch := range status.Fetches {
+			hf := healthFetch{
+				Success:             fetch.Success,
+				DurationSeconds:     fetch.Duration.Seconds(),
+				LastSuccess:         timeOrNil(fetch.LastSuccess),
+				LastError:           timeOrNil(fetch.LastError),
+				Error:               logger.Redact(fetch.Error),
+				ConsecutiveFailures: fetch.ConsecutiveFailures,
+			}
+			if !fetch.Success {
+				body.Status = "degraded"
+			}
+			body.Fetches[name] = hf
+		}
+		if status.Failing {
+			body.Status = "failing"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(body)
+}
+
+// timeOrNil returns nil for the zero time, so that it is left out of the JSON
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+	"github.com/eze-kiel/uptimerobot-exporter/secret"
+	"github.com/fsnotify/fsnotify"
+)
+
+// secretTimeout bounds each fetch of the API key from a secret store
+const secretTimeout = 30 * time.Second
+
+// newKeySource returns the secret store set by the flags to fetch the main API
+// key from, nil if there is none. It cannot be combined with the other ways of
+// giving the key.
+func (a *app) newKeySource() (secret.Source, error) {
+	if a.apiKeySource == "" && a.vault.SecretPath == "" {
+		return nil, nil
+	}
+	if a.apiKeySource != "" && a.vault.SecretPath != "" {
+		return nil, errors.New("-api-key-source and -vault.secret-path are mutually exclusive")
+	}
+	if a.apiKey != "" || a.apiKeyFile != "" {
+		return nil, errors.New("-api-key-source and -vault.secret-path cannot be combined with -api-key, -api-key-file or UPTIMEROBOT_API_KEY env variable")
+	}
+	if a.apiKeySource != "" {
+		return secret.Parse(a.apiKeySource)
+	}
+
+	opts := a.vault
+	if opts.Address == "" {
+		opts.Address = os.Getenv("VAULT_ADDR")
+	}
+	if opts.AuthMethod == secret.VaultAuthToken {
+		opts.Token = os.Getenv("VAULT_TOKEN")
+		if a.vaultTokenFile != "" {
+			content, err := ioutil.ReadFile(a.vaultTokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read Vault token: %w", err)
+			}
+			opts.Token = strings.TrimSpace(string(content))
+		}
+		logger.AddSecret(opts.Token)
+	}
+	return secret.NewVault(opts)
+}
+
+// fetchAPIKey fetches the main API key from the secret store
+func (a *app) fetchAPIKey() (string, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, secretTimeout)
+	defer cancel()
+	apiKey, err := a.keySource.APIKey(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch API key: %w", err)
+	}
+	return apiKey, nil
+}
+
+// refreshAPIKey fetches the main API key from the secret store every interval
+// until the exporter stops, so that a rotated key is picked up without
+// restarting. The previous key is kept when the fetch fails.
+func (a *app) refreshAPIKey(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		apiKey, err := a.fetchAPIKey()
+		if err != nil {
+			a.logger.Error().Err(err).Msg("cannot refresh API key, keeping the current one")
+			continue
+		}
+
+		a.rotateAPIKey(apiKey)
+	}
+}
+
+// watchAPIKeyFile re-reads -api-key-file each time it changes until the
+// exporter stops, so that a rotated key is picked up without a reload. The
+// directory of the file is watched rather than the file, to also catch files
+// replaced by a rename or by a symlink swap such as Kubernetes secret updates.
+func (a *app) watchAPIKeyFile() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(a.apiKeyFile)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-a.ctx.Done():
+				return
+			case err := <-watcher.Errors:
+				a.logger.Error().Err(err).Msg("cannot watch API key file")
+			case event := <-watcher.Events:
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				apiKey, err := a.readAPIKeyFile()
+				if err != nil {
+					// the file may be missing while it is replaced
+					a.logger.Debug().Err(err).Msg("cannot read API key file, keeping the current key")
+					continue
+				}
+				a.rotateAPIKey(apiKey)
+			}
+		}
+	}()
+	return nil
+}
+
+// rotateAPIKey replaces the main API key when it differs from the current one.
+// The new key is checked without holding mu, so that the requests are not
+// blocked meanwhile, and only installed once Uptime Robot accepted it.
+func (a *app) rotateAPIKey(apiKey string) {
+	a.keyMu.Lock()
+	defer a.keyMu.Unlock()
+
+	a.mu.RLock()
+	client := a.client
+	a.mu.RUnlock()
+	if client == nil || client.APIKey() == apiKey || apiKey == a.rejectedKey {
+		return
+	}
+
+	checked, err := a.checkNewAPIKey(apiKey)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("rotated API key rejected, keeping the current one")
+		a.rejectedKey = apiKey
+		return
+	}
+	a.logger.Info().Msg("API key rotated")
+	client.UseAPIKeyOf(checked)
+	a.keyReloads.Inc()
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// KitLogger writes the logs of the libraries logging with go-kit, such as the
+// Prometheus exporter toolkit, to a zerolog logger
+type KitLogger struct {
+	logger zerolog.Logger
+}
+
+// NewKitLogger returns a go-kit logger writing to logger
+func NewKitLogger(logger zerolog.Logger) KitLogger {
+	return KitLogger{logger: logger}
+}
+
+// Log implements the go-kit Logger interface. The msg and level keys become
+// the message and the level of the log line, and the other keys its fields.
+func (l KitLogger) Log(keyvals ...interface{}) error {
+	lvl := zerolog.InfoLevel
+	var msg string
+	fields := make(map[string]interface{}, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		switch key {
+		case "msg":
+			msg = fmt.Sprint(keyvals[i+1])
+		case "level":
+			if parsed, err := zerolog.ParseLevel(fmt.Sprint(keyvals[i+1])); err == nil {
+				lvl = parsed
+			}
+		default:
+			fields[key] = keyvals[i+1]
+		}
+	}
+	l.logger.WithLevel(lvl).Fields(fields).Msg(msg)
+	return nil
+}
@@ -7,7 +7,8 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// New creates a new zerolog logger
+// New creates a new zerolog logger, masking the secrets registered with
+// AddSecret and the passwords of the URLs in the log lines
 func New(level string) zerolog.Logger {
 	lvl, err := zerolog.ParseLevel(level)
 	if err != nil {
@@ -15,6 +16,16 @@ func New(level string) zerolog.Logger {
 		lvl = zerolog.InfoLevel
 	}
 	zerolog.SetGlobalLevel(lvl)
-	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
+	logger := zerolog.New(redactWriter{os.Stderr}).With().Timestamp().Logger()
 	return logger
 }
+
+// SetLevel changes the level of all the loggers
+func SetLevel(level string) error {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	zerolog.SetGlobalLevel(lvl)
+	return nil
+}
@@ -1,264 +1,858 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"flag"
 
+	"github.com/eze-kiel/uptimerobot-exporter/collector"
 	"github.com/eze-kiel/uptimerobot-exporter/logger"
+	"github.com/eze-kiel/uptimerobot-exporter/secret"
+	"github.com/eze-kiel/uptimerobot-exporter/sink"
+	"github.com/eze-kiel/uptimerobot-exporter/systemd"
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/version"
+	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/rs/zerolog"
 )
 
+// shutdownTimeout is how long in-flight scrapes are waited for when stopping
+const shutdownTimeout = 10 * time.Second
+
+// validMetricsPrefix matches the prefixes making valid metric names
+var validMetricsPrefix = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
 type app struct {
-	apiKey         string
-	address        string
-	port           string
-	scrapeInterval int
-	logLevel       string
-	logger         zerolog.Logger
+	apiKey             string
+	apiKeyFile         string
+	apiKeySource       string
+	vault              secret.VaultOptions
+	vaultTokenFile     string
+	vaultRefresh       time.Duration
+	keySource          secret.Source
+	keyReloads         prometheus.Counter
+	address            string
+	port               string
+	listenAddresses    listenAddresses
+	listenSocket       string
+	allowedCIDRs       string
+	webBearerToken     string
+	webBearerTokenFile string
+	scrapeRate         float64
+	scrapeBurst        int
+	allowedNetworks    []*net.IPNet
+	webConfigFile      string
+	debugAPI           bool
+	accessLogs         bool
+	readHeaderTimeout  time.Duration
+	readTimeout        time.Duration
+	writeTimeout       time.Duration
+	idleTimeout        time.Duration
+	tlsCertFile        string
+	tlsKeyFile         string
+	tlsClientCAFile    string
+	scrapeInterval     int
+	accountEvery       time.Duration
+	monitorsEvery      time.Duration
+	jitter             time.Duration
+	maxFailed          int
+	rtWindow           time.Duration
+	legacyAccount      bool
+	redactPII          bool
+	legacyLabels       bool
+	lowChurn           bool
+	legacyRTUnits      bool
+	rtTimestamps       bool
+	rtHistogram        bool
+	pushGatewayURL     string
+	pushJob            string
+	pushGrouping       constLabels
+	pushInterval       time.Duration
+	influxDB           sink.InfluxDBOptions
+	influxTokenFile    string
+	influxInterval     time.Duration
+	graphite           sink.GraphiteOptions
+	graphiteInterval   time.Duration
+	statsd             sink.StatsDOptions
+	statsdInterval     time.Duration
+	otlp               sink.OTLPOptions
+	otlpHeaders        keyValues
+	otlpResource       keyValues
+	otlpInterval       time.Duration
+	cloudWatch         sink.CloudWatchOptions
+	cloudWatchDims     string
+	cloudWatchInterval time.Duration
+	datadog            sink.DatadogOptions
+	datadogKeyFile     string
+	datadogTags        string
+	datadogInterval    time.Duration
+	textfileDirectory  string
+	textfileInterval   time.Duration
+	sinkMetricsFlag    string
+	sinkMetrics        *regexp.Regexp
+	sinks              []scheduledSink
+	labelsFlag         string
+	idsFlag            string
+	monitorIDs         []int
+	typesFlag          string
+	monitorTypes       []int
+	statusesFlag       string
+	monitorStatuses    []int
+	monitorSearch      string
+	tagsFlag           string
+	skipPaused         bool
+	includeFlag        string
+	excludeFlag        string
+	includeMonitors    *regexp.Regexp
+	excludeMonitors    *regexp.Regexp
+	nameLabels         []*regexp.Regexp
+	labels             []string
+	monitorLabelsFile  string
+	labelMappings      *collector.LabelMappings
+	overrides          *collector.MonitorOverrides
+	filters            *collector.MonitorFilters
+	metricsPrefix      string
+	constLabels        constLabels
+	onDemand           bool
+	apiURL             string
+	apiTimeout         time.Duration
+	proxyURL           string
+	proxy              *url.URL
+	apiWorkers         int
+	apiRetry           uptimerobot.RetryPolicy
+	apiBreaker         uptimerobot.BreakerPolicy
+	logLevel           string
+	configFile         string
+	noFailOnAuth       bool
+	once               bool
+	showVersion        bool
+	checkAPI           bool
+	logger             zerolog.Logger
+
+	// ctx is cancelled when the exporter receives SIGINT or SIGTERM
+	ctx context.Context
+
+	// flagSources holds the source of the flags given on the command line or
+	// by their env variable, which take precedence over the configuration file
+	flagSources map[string]settingSource
+
+	// keyMu serializes the reloads and the rotations of the main API key,
+	// which check the keys against the API without holding mu
+	keyMu sync.Mutex
+	// rejectedKey is the latest rotated API key rejected by Uptime Robot,
+	// which is not checked again each time the key file directory changes
+	rejectedKey string
+
+	// mu protects the fields below, which are updated on reload
+	mu sync.RWMutex
+
+	// client and collector serve the account of the main API key on /metrics
+	client    *uptimerobot.Client
+	collector *collector.Collector
+
+	// intervals are the current polling intervals, and stopPolling stops the
+	// polling routines started with them
+	intervals   collector.Intervals
+	stopPolling context.CancelFunc
+
+	// authUsers holds the bcrypt hashes of the passwords of the users allowed
+	// to query the exporter, by name
+	authUsers map[string]string
+
+	// bearerToken is the token of -web.bearer-token or -web.bearer-token-file
+	// allowed to query the exporter
+	bearerToken string
+
+	// accounts holds the accounts defined in the configuration file, by name
+	accounts map[string]*account
 }
 
-type AccountDetails struct {
-	Stat    string `json:"stat"`
-	Account struct {
-		Email                  string    `json:"email"`
-		UserID                 int       `json:"user_id"`
-		Firstname              string    `json:"firstname"`
-		SmsCredits             int       `json:"sms_credits"`
-		PaymentProcessor       int       `json:"payment_processor"`
-		PaymentPeriod          int       `json:"payment_period"`
-		SubscriptionExpiryDate time.Time `json:"subscription_expiry_date"`
-		MonitorLimit           int       `json:"monitor_limit"`
-		MonitorInterval        int       `json:"monitor_interval"`
-		UpMonitors             int       `json:"up_monitors"`
-		DownMonitors           int       `json:"down_monitors"`
-		PausedMonitors         int       `json:"paused_monitors"`
-	} `json:"account"`
+// account is an account served on /probe. Its collector is kept between
+// requests so that the state it builds across scrapes is not lost.
+type account struct {
+	client    *uptimerobot.Client
+	collector *collector.Collector
 }
 
-type MonitorsData struct {
-	Stat       string `json:"stat"`
-	Pagination struct {
-		Offset int `json:"offset"`
-		Limit  int `json:"limit"`
-		Total  int `json:"total"`
-	} `json:"pagination"`
-	Monitors []Monitor `json:"monitors"`
+// commands are the subcommands of the exporter, serve being the default one
+var commands = []struct {
+	name  string
+	usage string
+}{
+	{"serve", "Serve the Uptime Robot metrics over HTTP (default)"},
+	{"check", "Fetch the Uptime Robot data once, print the metrics on stdout and exit"},
+	{"check-config", "Validate the flags and the configuration files, and exit"},
+	{"version", "Print the version and exit"},
 }
 
-type Monitor struct {
-	ID             int    `json:"id"`
-	FriendlyName   string `json:"friendly_name"`
-	URL            string `json:"url"`
-	Type           int    `json:"type"`
-	SubType        string `json:"sub_type"`
-	KeywordType    int    `json:"keyword_type"`
-	KeywordValue   string `json:"keyword_value"`
-	HTTPUsername   string `json:"http_username"`
-	HTTPPassword   string `json:"http_password"`
-	Port           string `json:"port"`
-	Interval       int    `json:"interval"`
-	Status         int    `json:"status"`
-	CreateDatetime int    `json:"create_datetime"`
-	ResponseTimes  []struct {
-		Datetime int `json:"datetime"`
-		Value    int `json:"value"`
-	} `json:"response_times"`
-	AverageResponseTime json.Number `json:"average_response_time"`
+func main() {
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var a app
+	switch command {
+	case "serve":
+		a.parseFlags(command, args)
+		a.serve()
+	case "check":
+		a.parseFlags(command, args)
+		a.check()
+	case "check-config":
+		a.parseFlags(command, args)
+		a.checkConfig()
+	case "version":
+		printVersion()
+	case "help":
+		a.flagSet(command).Usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		a.flagSet(command).Usage()
+		os.Exit(2)
+	}
 }
 
-var (
-	accountDetails = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "uptimerobot_account_details",
-		Help: "Details of the Uptime Robot account",
-	}, []string{"firstname", "email", "monitors_limit", "monitor_interval", "up_monitors", "down_monitors", "paused_monitors", "payment_period"})
+// printVersion prints the version, the revision, the build date and the Go
+// version of the exporter, which are set at build time with -ldflags
+func printVersion() {
+	fmt.Println(version.Print("uptimerobot-exporter"))
+}
 
-	upMonitors = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "uptimerobot_up_monitors",
-		Help: "Up monitors",
-	})
+// flagSet returns the flags of command. The commands share most of them.
+func (a *app) flagSet(command string) *flag.FlagSet {
+	fs := flag.NewFlagSet("uptimerobot-exporter "+command, flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintln(out, "Usage: uptimerobot-exporter [command] [flags]")
+		fmt.Fprintln(out, "\nCommands:")
+		for _, c := range commands {
+			fmt.Fprintf(out, "  %-14s%s\n", c.name, c.usage)
+		}
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&a.apiKey, "api-key", "", "Uptime Robot API key")
+	fs.StringVar(&a.apiKeyFile, "api-key-file", "", "Path to a file holding the Uptime Robot API key, read again on reload")
+	fs.StringVar(&a.apiKeySource, "api-key-source", "", "Secret store the Uptime Robot API key is fetched from at startup and on reload, as aws-secretsmanager://name[#field] or aws-ssm://name")
+	fs.StringVar(&a.vault.Address, "vault.address", "", "Address of the Vault server holding the Uptime Robot API key (defaults to VAULT_ADDR env variable)")
+	fs.StringVar(&a.vault.AuthMethod, "vault.auth-method", secret.VaultAuthToken, "Vault authentication method, among token, kubernetes and approle")
+	fs.StringVar(&a.vault.AuthMount, "vault.auth-mount", "", "Path the Vault authentication method is mounted on (defaults to -vault.auth-method)")
+	fs.StringVar(&a.vaultTokenFile, "vault.token-file", "", "Path to a file holding the Vault token of the token authentication method (defaults to VAULT_TOKEN env variable)")
+	fs.StringVar(&a.vault.Role, "vault.role", "", "Vault role of the kubernetes authentication method")
+	fs.StringVar(&a.vault.JWTFile, "vault.jwt-file", secret.DefaultKubernetesJWTFile, "Path to the service account token of the kubernetes authentication method")
+	fs.StringVar(&a.vault.RoleID, "vault.role-id", "", "Role ID of the approle authentication method")
+	fs.StringVar(&a.vault.SecretIDFile, "vault.secret-id-file", "", "Path to a file holding the secret ID of the approle authentication method")
+	fs.StringVar(&a.vault.SecretPath, "vault.secret-path", "", "Path of the Vault secret holding the Uptime Robot API key, such as secret/data/uptimerobot, enabling Vault")
+	fs.StringVar(&a.vault.SecretField, "vault.secret-field", "api_key", "Field of the Vault secret holding the Uptime Robot API key")
+	fs.DurationVar(&a.vaultRefresh, "vault.refresh-interval", 5*time.Minute, "Interval at which the Uptime Robot API key is fetched again from Vault (0 disables it)")
+	fs.StringVar(&a.address, "ip", "0.0.0.0", "IP on which the Prometheus server will be binded")
+	fs.StringVar(&a.port, "p", "9705", "Port that will be used by the Prometheus server")
+	fs.Var(&a.listenAddresses, "web.listen-address", "Address to listen on instead of -ip and -p, such as [::1]:9705 (repeatable)")
+	fs.StringVar(&a.listenSocket, "web.listen-socket", "", "Path to a Unix domain socket to listen on instead of -ip and -p, such as /run/uptimerobot-exporter.sock")
+	fs.DurationVar(&a.readHeaderTimeout, "web.read-header-timeout", 10*time.Second, "Time allowed to read the headers of an HTTP request (0 means no timeout)")
+	fs.DurationVar(&a.readTimeout, "web.read-timeout", 30*time.Second, "Time allowed to read a whole HTTP request (0 means no timeout)")
+	fs.DurationVar(&a.writeTimeout, "web.write-timeout", 2*time.Minute, "Time allowed to serve an HTTP request, including the on-demand API calls (0 means no timeout)")
+	fs.DurationVar(&a.idleTimeout, "web.idle-timeout", 2*time.Minute, "Time an idle keep-alive connection is kept open (0 means -web.read-timeout)")
+	fs.BoolVar(&a.accessLogs, "web.access-log", false, "Log each HTTP request served at the info level instead of the debug level")
+	fs.BoolVar(&a.debugAPI, "web.enable-debug-api", false, "Serve the latest raw answers of the Uptime Robot API on /debug/api, with the secrets masked (requires basic or bearer token authentication)")
+	fs.StringVar(&a.allowedCIDRs, "web.allowed-cidrs", "", "Comma-separated networks allowed to query the exporter, such as 10.0.0.0/8,2001:db8::/32, the others getting 403 except on /health and /-/ready (default: all)")
+	fs.StringVar(&a.webBearerToken, "web.bearer-token", "", "Bearer token required to query the exporter, except on /health and /-/ready")
+	fs.StringVar(&a.webBearerTokenFile, "web.bearer-token-file", "", "Path to a file holding the bearer token required to query the exporter, read again on reload")
+	fs.Float64Var(&a.scrapeRate, "web.rate-limit", 0, "Maximum number of requests per second of /metrics, /probe and /api/v1/monitors allowed to each client IP, the others getting 429 (0 means no limit)")
+	fs.IntVar(&a.scrapeBurst, "web.rate-limit-burst", 5, "Number of scrapes a client IP can make in a row before being held to -web.rate-limit")
+	fs.StringVar(&a.webConfigFile, "web.config.file", "", "Path to an exporter toolkit web configuration file, setting TLS, basic authentication and HTTP/2 like for the official exporters")
+	fs.StringVar(&a.tlsCertFile, "tls-cert-file", "", "Path to the TLS certificate serving the metrics over HTTPS, reloaded when it changes (requires -tls-key-file)")
+	fs.StringVar(&a.tlsKeyFile, "tls-key-file", "", "Path to the private key of -tls-cert-file")
+	fs.StringVar(&a.tlsClientCAFile, "tls-client-ca-file", "", "Path to the PEM CA certificates verifying the client certificates, which are then required (requires -tls-cert-file)")
+	fs.IntVar(&a.scrapeInterval, "interval", 30, "Uptime robot API scrape interval, in seconds (ignored with -on-demand)")
+	fs.DurationVar(&a.accountEvery, "account-interval", 0, "Account details polling interval (defaults to -interval, ignored with -on-demand)")
+	fs.DurationVar(&a.monitorsEvery, "monitors-interval", 0, "Monitors polling interval (defaults to -interval, ignored with -on-demand)")
+	fs.DurationVar(&a.jitter, "jitter", 0, "Maximum random delay added before each API poll, to spread the calls of exporters started together (ignored with -on-demand)")
+	fs.IntVar(&a.maxFailed, "max-failed-fetches", 0, "Number of consecutive failed API fetches, by scrape or poll, after which the metrics are dropped instead of serving old values (0 means never)")
+	fs.DurationVar(&a.rtWindow, "response-time-window", time.Hour, "Rolling window of the response time quantiles, built from the successive API calls (0 disables them)")
+	fs.BoolVar(&a.rtTimestamps, "response-time-timestamps", false, "Export the latest response time with the date of the check it comes from instead of the scrape time")
+	fs.BoolVar(&a.rtHistogram, "response-time-histogram", false, "Export uptimerobot_check_response_time_seconds, a histogram of the response times of the checks with the latest one as exemplar in the OpenMetrics format")
+	fs.BoolVar(&a.legacyAccount, "legacy-account-details", false, "Also export uptimerobot_account_details, holding the account numbers in labels")
+	fs.BoolVar(&a.redactPII, "redact-account-pii", false, "Leave the firstname and email labels of uptimerobot_account_details empty")
+	fs.BoolVar(&a.legacyLabels, "legacy-monitor-labels", false, "Export uptimerobot_monitors_status and uptimerobot_response_time_seconds without the monitor_id label, keeping a single monitor when several share the same labels")
+	fs.BoolVar(&a.lowChurn, "low-churn-labels", false, "Export uptimerobot_monitors_status and uptimerobot_response_time_seconds without the interval and type labels, which are found in uptimerobot_monitor_info and uptimerobot_monitor_interval_seconds")
+	fs.BoolVar(&a.legacyRTUnits, "legacy-response-time-units", false, "Export the response times in milliseconds, as uptimerobot_response_time, uptimerobot_response_time_average and uptimerobot_response_time_rolling")
+	fs.StringVar(&a.idsFlag, "monitor-ids", "", "Comma-separated IDs of the exported monitors (default: all)")
+	fs.StringVar(&a.typesFlag, "monitor-types", "", "Comma-separated types of the exported monitors, among http, keyword, ping, port and heartbeat (default: all)")
+	fs.StringVar(&a.statusesFlag, "monitor-statuses", "", "Comma-separated statuses of the exported monitors, among paused, not_checked, up, seems_down and down (default: all)")
+	fs.StringVar(&a.monitorSearch, "monitor-search", "", "Keyword restricting the exported monitors to the ones whose URL or friendly name contains it, searched by the Uptime Robot API")
+	fs.StringVar(&a.tagsFlag, "tags", "", "Comma-separated Uptime Robot tags, restricting the exported monitors to the ones having at least one of them (default: all)")
+	fs.StringVar(&a.includeFlag, "include-monitors", "", "Regular expression restricting the exported monitors to the ones whose friendly name or URL it matches")
+	fs.StringVar(&a.excludeFlag, "exclude-monitors", "", "Regular expression excluding the monitors whose friendly name or URL it matches")
+	fs.BoolVar(&a.skipPaused, "skip-paused", false, "Leave the paused monitors out of the per-monitor metrics, while still counting them in uptimerobot_paused_monitors and uptimerobot_monitors_by_status")
+	fs.StringVar(&a.labelsFlag, "labels", "", "Comma-separated monitor attributes used as labels of the per-monitor metrics, among id, url, friendly_name, type, interval and port (overrides -legacy-monitor-labels and -low-churn-labels)")
+	fs.StringVar(&a.metricsPrefix, "metrics-prefix", uptimerobot.DefaultMetricsPrefix, "Prefix of the names of the exported metrics")
+	fs.Var(&a.constLabels, "const-label", "Label added to all the exported metrics, as name=value (repeatable, or comma-separated)")
+	fs.BoolVar(&a.onDemand, "on-demand", true, "Query the Uptime Robot API each time /metrics is scraped instead of polling it every -interval")
+	fs.StringVar(&a.apiURL, "api-url", uptimerobot.DefaultBaseURL, "Base URL of the Uptime Robot API")
+	fs.DurationVar(&a.apiTimeout, "api-timeout", 10*time.Second, "Timeout of each request made to the Uptime Robot API")
+	fs.StringVar(&a.proxyURL, "proxy-url", "", "HTTP, HTTPS or SOCKS5 proxy used to reach the Uptime Robot API (defaults to HTTP_PROXY, HTTPS_PROXY and NO_PROXY env variables)")
+	fs.IntVar(&a.apiWorkers, "api-workers", 4, "Number of getMonitors pages fetched concurrently")
+	fs.IntVar(&a.apiRetry.MaxAttempts, "api-max-attempts", 3, "Maximum number of attempts of a failing API call")
+	fs.DurationVar(&a.apiRetry.MaxElapsed, "api-max-retry-time", 30*time.Second, "Time after which a failing API call is not retried anymore (0 means no limit)")
+	fs.IntVar(&a.apiBreaker.Threshold, "api-breaker-threshold", 5, "Number of consecutive failed API calls suspending the calls (0 disables the circuit breaker)")
+	fs.DurationVar(&a.apiBreaker.Cooldown, "api-breaker-cooldown", time.Minute, "Time after which a call is attempted again once the circuit breaker is open")
+	fs.StringVar(&a.pushGatewayURL, "push.gateway-url", "", "URL of a Prometheus Pushgateway the metrics of the main account are pushed to, once by the check command instead of printing them, or every -push.interval while serving")
+	fs.StringVar(&a.pushJob, "push.job", "uptimerobot", "Job label of the metrics pushed to the Pushgateway")
+	fs.Var(&a.pushGrouping, "push.grouping-label", "Grouping label of the metrics pushed to the Pushgateway, as name=value (repeatable, or comma-separated)")
+	fs.DurationVar(&a.pushInterval, "push.interval", time.Minute, "Interval at which the metrics are pushed to the Pushgateway while serving")
+	fs.StringVar(&a.influxDB.URL, "influxdb.url", "", "Base URL of an InfluxDB server the metrics of the main account are written to every -influxdb.interval")
+	fs.StringVar(&a.influxDB.Database, "influxdb.database", "", "InfluxDB 1 database the metrics are written to")
+	fs.StringVar(&a.influxDB.RetentionPolicy, "influxdb.retention-policy", "", "InfluxDB 1 retention policy the metrics are written with (default: the one of the database)")
+	fs.StringVar(&a.influxDB.Org, "influxdb.org", "", "InfluxDB 2 organization the metrics are written to")
+	fs.StringVar(&a.influxDB.Bucket, "influxdb.bucket", "", "InfluxDB 2 bucket the metrics are written to, enabling the InfluxDB 2 API")
+	fs.StringVar(&a.influxDB.Token, "influxdb.token", "", "InfluxDB 2 API token")
+	fs.StringVar(&a.influxTokenFile, "influxdb.token-file", "", "Path to a file holding the InfluxDB 2 API token")
+	fs.DurationVar(&a.influxInterval, "influxdb.interval", time.Minute, "Interval at which the metrics are written to InfluxDB")
+	fs.StringVar(&a.graphite.Address, "graphite.address", "", "Address of a Graphite Carbon plaintext receiver the metrics of the main account are sent to every -graphite.interval, such as carbon:2003")
+	fs.StringVar(&a.graphite.Prefix, "graphite.prefix", "", "Prefix of the Graphite metric paths")
+	fs.BoolVar(&a.graphite.Tags, "graphite.tags", false, "Send the labels as Graphite tags instead of nodes of the metric paths (requires Graphite 1.1)")
+	fs.DurationVar(&a.graphiteInterval, "graphite.interval", time.Minute, "Interval at which the metrics are sent to Graphite")
+	fs.StringVar(&a.statsd.Address, "statsd.address", "", "Address of a StatsD server the metrics of the main account are sent to in UDP every -statsd.interval, such as localhost:8125")
+	fs.StringVar(&a.statsd.Prefix, "statsd.prefix", "", "Prefix of the StatsD metric names")
+	fs.BoolVar(&a.statsd.Tags, "statsd.tags", false, "Send the labels as DogStatsD tags instead of nodes of the metric names")
+	fs.DurationVar(&a.statsdInterval, "statsd.interval", time.Minute, "Interval at which the metrics are sent to StatsD")
+	fs.StringVar(&a.otlp.Endpoint, "otlp.endpoint", "", "Base URL of an OpenTelemetry OTLP/HTTP receiver the metrics of the main account are exported to every -otlp.interval, such as http://otel-collector:4318")
+	fs.Var(&a.otlpHeaders, "otlp.headers", "Header sent with the OTLP exports, as name=value (repeatable, or comma-separated)")
+	fs.Var(&a.otlpResource, "otlp.resource-attributes", "Resource attribute of the OTLP exports, as key=value (repeatable, or comma-separated), taking precedence over OTEL_RESOURCE_ATTRIBUTES")
+	fs.DurationVar(&a.otlpInterval, "otlp.interval", time.Minute, "Interval at which the metrics are exported with OTLP")
+	fs.StringVar(&a.cloudWatch.Namespace, "cloudwatch.namespace", "", "AWS CloudWatch namespace the metrics of the main account are published to every -cloudwatch.interval, such as UptimeRobot")
+	fs.StringVar(&a.cloudWatchDims, "cloudwatch.dimensions", "", "Comma-separated names of the labels published as CloudWatch dimensions (default: all, up to 10)")
+	fs.StringVar(&a.cloudWatch.Region, "cloudwatch.region", "", "AWS region the metrics are published to (defaults to the region of the AWS configuration)")
+	fs.DurationVar(&a.cloudWatchInterval, "cloudwatch.interval", time.Minute, "Interval at which the metrics are published to CloudWatch")
+	fs.StringVar(&a.datadog.APIKey, "datadog.api-key", "", "Datadog API key the metrics of the main account are submitted with to the Datadog metrics API every -datadog.interval")
+	fs.StringVar(&a.datadogKeyFile, "datadog.api-key-file", "", "Path to a file holding the Datadog API key")
+	fs.StringVar(&a.datadog.URL, "datadog.url", "https://api.datadoghq.com", "Base URL of the Datadog API of the site of the account, such as https://api.datadoghq.eu")
+	fs.StringVar(&a.datadogTags, "datadog.tags", "", "Comma-separated tags added to the metrics submitted to Datadog, such as env:prod")
+	fs.DurationVar(&a.datadogInterval, "datadog.interval", time.Minute, "Interval at which the metrics are submitted to Datadog")
+	fs.StringVar(&a.textfileDirectory, "textfile.directory", "", "Directory of the node exporter textfile collector the metrics of the main account are written to as uptimerobot.prom, once by the check command instead of printing them, or every -textfile.interval while serving")
+	fs.DurationVar(&a.textfileInterval, "textfile.interval", time.Minute, "Interval at which the textfile is written while serving")
+	fs.StringVar(&a.sinkMetricsFlag, "sinks.metrics", "", "Regular expression restricting the metrics written to InfluxDB, Graphite, StatsD, OTLP, CloudWatch, Datadog and the textfile to the ones whose name it matches (default: all)")
+	fs.StringVar(&a.logLevel, "log-level", "info", "Log level")
+	fs.BoolVar(&a.noFailOnAuth, "no-fail-on-auth-error", false, "Keep running when an API key is rejected at startup")
+	fs.BoolVar(&a.showVersion, "version", false, "Print the version and exit")
+	fs.BoolVar(&a.once, "once", false, "Deprecated, use the check command")
+	fs.StringVar(&a.monitorLabelsFile, "monitor-labels.file", "", "Path to a YAML file setting labels of the per-monitor metrics by monitor ID or URL, reloaded along with the configuration file")
+	fs.StringVar(&a.configFile, "config.file", "", "Path to a YAML configuration file, reloaded on SIGHUP")
+	if command == "check-config" {
+		fs.BoolVar(&a.checkAPI, "api", false, "Also check that the API keys are accepted by the Uptime Robot API")
+	}
+	return fs
+}
 
-	downMonitors = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "uptimerobot_down_monitors",
-		Help: "Down monitors",
-	})
+// parseFlags parses the flags of command given in args and validates them,
+// exiting on error
+func (a *app) parseFlags(command string, args []string) {
+	fs := a.flagSet(command)
+	fs.Parse(args)
+	if a.showVersion {
+		printVersion()
+		os.Exit(0)
+	}
+	var err error
+	if a.flagSources, err = setFlagsFromEnv(fs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	pausedMonitors = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "uptimerobot_paused_monitors",
-		Help: "Down monitors",
-	})
+	a.logger = logger.New(a.logLevel)
 
-	monitorsStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "uptimerobot_monitors_status",
-		Help: "The total number of processed events",
-	}, []string{"url", "friendly_name", "interval"})
+	if !validMetricsPrefix.MatchString(a.metricsPrefix) {
+		a.logger.Fatal().Msgf("invalid -metrics-prefix %q, it must start with a letter, _ or : followed by letters, digits, _ or :", a.metricsPrefix)
+	}
 
-	responseTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "uptimerobot_response_time",
-		Help: "Monitors response times",
-	}, []string{"url", "friendly_name", "type"})
-)
+	if a.idsFlag != "" {
+		if a.monitorIDs, err = parseMonitorIDs(a.idsFlag); err != nil {
+			a.logger.Fatal().Err(err).Msg("invalid -monitor-ids")
+		}
+	}
+	if a.typesFlag != "" {
+		if a.monitorTypes, err = collector.ParseMonitorTypes(strings.Split(a.typesFlag, ",")); err != nil {
+			a.logger.Fatal().Err(err).Msg("invalid -monitor-types")
+		}
+	}
 
-func main() {
-	var a app
-	flag.StringVar(&a.apiKey, "api-key", "", "Uptime Robot API key")
-	flag.StringVar(&a.address, "ip", "0.0.0.0", "IP on which the Prometheus server will be binded")
-	flag.StringVar(&a.port, "p", "9705", "Port that will be used by the Prometheus server")
-	flag.IntVar(&a.scrapeInterval, "interval", 30, "Uptime robot API scrape interval, in seconds")
-	flag.StringVar(&a.logLevel, "log-level", "info", "Log level")
-	flag.Parse()
+	if a.statusesFlag != "" {
+		if a.monitorStatuses, err = collector.ParseMonitorStatuses(strings.Split(a.statusesFlag, ",")); err != nil {
+			a.logger.Fatal().Err(err).Msg("invalid -monitor-statuses")
+		}
+	}
+	for _, filter := range []struct {
+		flag  string
+		value string
+		re    **regexp.Regexp
+	}{
+		{"include-monitors", a.includeFlag, &a.includeMonitors},
+		{"exclude-monitors", a.excludeFlag, &a.excludeMonitors},
+		{"sinks.metrics", a.sinkMetricsFlag, &a.sinkMetrics},
+	} {
+		if filter.value == "" {
+			continue
+		}
+		re, err := regexp.Compile(filter.value)
+		if err != nil {
+			a.logger.Fatal().Err(err).Msgf("invalid -%s", filter.flag)
+		}
+		*filter.re = re
+	}
 
-	a.logger = logger.New(a.logLevel)
-	if a.apiKey == "" {
-		a.apiKey = os.Getenv("UPTIMEROBOT_API_KEY")
-		if a.apiKey == "" {
-			a.logger.Fatal().Err(errors.New("missing Uptime Robot API key")).Msg("use -api-key or UPTIMEROBOT_API_KEY env variable")
+	if len(a.listenAddresses) > 0 {
+		if a.listenSocket != "" {
+			a.logger.Fatal().Msg("-web.listen-address and -web.listen-socket are mutually exclusive")
+		}
+		for _, name := range []string{"ip", "p"} {
+			if _, ok := a.flagSources[name]; ok {
+				a.logger.Fatal().Msgf("-web.listen-address and -%s are mutually exclusive", name)
+			}
 		}
 	}
-	a.logger.Info().Msg("API key found")
-	a.logger.Info().Msg("starting fetch routines")
 
-	go a.fetchAccountDetails()
-	go a.fetchMonitors()
+	if a.allowedCIDRs != "" {
+		if a.listenSocket != "" {
+			a.logger.Fatal().Msg("-web.allowed-cidrs cannot be used with -web.listen-socket, restrict the permissions of the socket instead")
+		}
+		if a.allowedNetworks, err = parseCIDRs(a.allowedCIDRs); err != nil {
+			a.logger.Fatal().Err(err).Msg("invalid -web.allowed-cidrs")
+		}
+	}
 
-	a.logger.Info().Msg("starting metrics server")
-	http.Handle("/metrics", promhttp.Handler())
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintln(w, "I'm alive! 8)")
-	})
+	if a.pushGatewayURL != "" {
+		u, err := url.Parse(a.pushGatewayURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			a.logger.Fatal().Msgf("invalid -push.gateway-url %q, it must be an http or https URL", logger.Redact(a.pushGatewayURL))
+		}
+		if a.pushJob == "" {
+			a.logger.Fatal().Msg("-push.job cannot be empty")
+		}
+		if _, ok := a.pushGrouping["job"]; ok {
+			a.logger.Fatal().Msg("the job grouping label is set by -push.job")
+		}
+		if a.pushInterval <= 0 {
+			a.logger.Fatal().Msg("-push.interval must be positive")
+		}
+	}
 
-	if err := http.ListenAndServe(a.address+":"+a.port, nil); err != nil {
-		a.logger.Fatal().Err(err).Msg("Metrics server failed")
+	if a.sinks, err = a.newSinks(); err != nil {
+		a.logger.Fatal().Err(err).Msg("invalid sink")
+	}
+
+	if a.webBearerToken != "" && a.webBearerTokenFile != "" {
+		a.logger.Fatal().Msg("-web.bearer-token and -web.bearer-token-file are mutually exclusive")
+	}
+
+	if a.scrapeRate < 0 {
+		a.logger.Fatal().Msg("-web.rate-limit cannot be negative")
+	}
+	if a.scrapeRate > 0 && a.scrapeBurst < 1 {
+		a.logger.Fatal().Msg("-web.rate-limit-burst must be at least 1")
+	}
+
+	if (a.tlsCertFile == "") != (a.tlsKeyFile == "") {
+		a.logger.Fatal().Msg("-tls-cert-file and -tls-key-file must be set together")
+	}
+	if a.tlsClientCAFile != "" && a.tlsCertFile == "" {
+		a.logger.Fatal().Msg("-tls-client-ca-file requires -tls-cert-file and -tls-key-file")
+	}
+	if a.webConfigFile != "" {
+		if a.tlsCertFile != "" {
+			a.logger.Fatal().Msg("-web.config.file and -tls-cert-file are mutually exclusive, set TLS in the web configuration file")
+		}
+		if err := web.Validate(a.webConfigFile); err != nil {
+			a.logger.Fatal().Err(err).Msg("invalid -web.config.file")
+		}
+	}
+
+	if a.proxyURL != "" {
+		if a.proxy, err = parseProxyURL(a.proxyURL); err != nil {
+			a.logger.Fatal().Err(err).Msg("invalid -proxy-url")
+		}
+	}
+
+	if a.keySource, err = a.newKeySource(); err != nil {
+		a.logger.Fatal().Err(err).Msg("invalid API key source")
+	}
+}
+
+// check fetches the Uptime Robot data once and prints the metrics on stdout
+func (a *app) check() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a.ctx = ctx
+
+	if err := a.runOnce(); err != nil {
+		a.logger.Fatal().Err(err).Msg("cannot fetch Uptime Robot metrics")
 	}
 }
 
-func (a app) fetchAccountDetails() {
-	ticker := time.NewTicker(time.Duration(a.scrapeInterval) * time.Second)
-	for {
-		<-ticker.C
-		a.logger.Info().Msg("fetching account details")
-		data := url.Values{
-			"api_key": {a.apiKey},
-			"format":  {"json"},
+// serve serves the Uptime Robot metrics over HTTP until SIGINT or SIGTERM is
+// received
+func (a *app) serve() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a.ctx = ctx
+
+	if a.once {
+		a.logger.Warn().Msg("-once is deprecated, use the check command")
+		if err := a.runOnce(); err != nil {
+			a.logger.Fatal().Err(err).Msg("cannot fetch Uptime Robot metrics")
 		}
+		return
+	}
 
-		resp, err := http.PostForm("https://api.uptimerobot.com/v2/getAccountDetails", data)
-		if err != nil {
-			a.logger.Error().Err(err).Msg("failed to fetch account details")
-			continue
+	a.logger.Info().Msgf("starting uptimerobot-exporter %s", version.Info())
+
+	a.keyReloads = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: a.metricsPrefix + "api_key_reloads_total",
+		Help: "Number of times the main API key was replaced by a rotated one without restarting",
+	})
+	if err := a.loadConfig(); err != nil {
+		a.logger.Fatal().Err(err).Msg("cannot load configuration")
+	}
+	go a.reloadOnSIGHUP()
+	if a.vault.SecretPath != "" && a.vaultRefresh > 0 {
+		go a.refreshAPIKey(a.vaultRefresh)
+	}
+
+	if a.apiKeyFile != "" && a.apiKey == "" {
+		if err := a.watchAPIKeyFile(); err != nil {
+			a.logger.Fatal().Err(err).Msg("cannot watch API key file")
 		}
+	}
+
+	a.registerer(prometheus.DefaultRegisterer).MustRegister(version.NewCollector(a.metricsPrefix+"exporter"), a.keyReloads)
 
-		body, err := ioutil.ReadAll(resp.Body)
-		resp.Body.Close()
+	if a.pushGatewayURL != "" || len(a.sinks) > 0 {
+		registry, err := a.mainRegistry()
 		if err != nil {
-			a.logger.Error().Err(err).Msg("cannot parse response body")
-			continue
+			a.logger.Fatal().Err(err).Msg("cannot export the metrics")
+		}
+		var intervals []time.Duration
+		if a.pushGatewayURL != "" {
+			intervals = append(intervals, a.pushInterval)
+		}
+		for _, s := range a.sinks {
+			intervals = append(intervals, s.interval)
 		}
+		shared := newSharedGatherer(registry, intervals)
+		if a.pushGatewayURL != "" {
+			go a.pushEvery(ctx, shared, a.pushInterval)
+		}
+		for _, s := range a.sinks {
+			go a.writeEvery(ctx, s, shared)
+		}
+	}
 
-		var account AccountDetails
-		if err := json.Unmarshal(body, &account); err != nil {
-			a.logger.Error().Err(err).Msg("cannot parse JSON")
-			continue
+	a.logger.Info().Msg("starting metrics server")
+	limit := func(h http.Handler) http.Handler { return h }
+	if a.scrapeRate > 0 {
+		rejected := prometheus.NewCounter(prometheus.CounterOpts{
+			Name: a.metricsPrefix + "scrapes_rate_limited_total",
+			Help: "Number of scrapes rejected because their client exceeded -web.rate-limit",
+		})
+		a.registerer(prometheus.DefaultRegisterer).MustRegister(rejected)
+		limit = newScrapeLimiter(a.scrapeRate, a.scrapeBurst, rejected).limit
+	}
+	metrics := promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})
+	http.Handle("/metrics", limit(promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, metrics)))
+	http.Handle("/metrics/", limit(http.HandlerFunc(a.accountHandler)))
+	http.Handle("/probe", limit(http.HandlerFunc(a.probeHandler)))
+	http.HandleFunc("/-/reload", a.reloadHandler)
+	http.HandleFunc("/-/ready", a.readyHandler)
+	http.HandleFunc("/health", a.healthHandler)
+	http.Handle("/api/v1/monitors", limit(http.HandlerFunc(a.monitorsHandler)))
+	http.Handle("/sd", limit(http.HandlerFunc(a.sdHandler)))
+	if a.debugAPI {
+		// loadConfig made sure that it requires authentication
+		http.HandleFunc("/debug/api", a.debugAPIHandler)
+	}
+
+	servers, err := a.startServers(a.accessLog(a.allowNetworks(a.authenticate(http.DefaultServeMux))))
+	if err != nil {
+		a.logger.Fatal().Err(err).Msg("Metrics server failed")
+	}
+	go a.notifySystemd(ctx)
+
+	<-ctx.Done()
+	stop()
+	a.logger.Info().Msg("shutting down")
+	a.sdNotify(systemd.Stopping)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			a.logger.Error().Err(err).Msg("cannot gracefully stop the metrics server")
 		}
+	}
+}
+
+// mainRegistry returns a registry holding the metrics of the main account
+// only, for the exports other than /metrics
+func (a *app) mainRegistry() (*prometheus.Registry, error) {
+	a.mu.RLock()
+	c, client := a.collector, a.client
+	a.mu.RUnlock()
+	if c == nil {
+		return nil, errors.New("the API key of the main account is required to push or write the metrics")
+	}
+	registry := prometheus.NewRegistry()
+	if err := a.register(registry, c, client); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
 
-		a.logger.Debug().Msg("updating account details metrics")
-		upMonitors.Set(float64(account.Account.UpMonitors))
-		downMonitors.Set(float64(account.Account.DownMonitors))
-		pausedMonitors.Set(float64(account.Account.PausedMonitors))
+// readyHandler answers 200 once the initial fetch is over, so that no empty
+// metrics are served after a restart, and 503 before
+func (a *app) readyHandler(w http.ResponseWriter, r *http.Request) {
+	a.mu.RLock()
+	ready := a.collector == nil || a.collector.Ready()
+	a.mu.RUnlock()
 
-		accountDetails.WithLabelValues(account.Account.Firstname,
-			account.Account.Email,
-			strconv.Itoa(account.Account.MonitorLimit),
-			strconv.Itoa(account.Account.MonitorInterval),
-			strconv.Itoa(account.Account.UpMonitors),
-			strconv.Itoa(account.Account.DownMonitors),
-			strconv.Itoa(account.Account.PausedMonitors),
-			strconv.Itoa(account.Account.PaymentPeriod))
+	if !ready {
+		http.Error(w, "initial fetch in progress", http.StatusServiceUnavailable)
+		return
 	}
+	fmt.Fprintln(w, "Ready")
 }
 
-func (a app) fetchMonitors() {
-	ticker := time.NewTicker(time.Duration(a.scrapeInterval) * time.Second)
-	var previousMonitors MonitorsData
-	for {
-		<-ticker.C
-		a.logger.Info().Msg("fetching monitors")
-		data := url.Values{
-			"api_key":              {a.apiKey},
-			"format":               {"json"},
-			"response_times":       {"1"},
-			"response_times_limit": {"1"},
-		}
+// clientOptions returns the Uptime Robot API client options set by the flags
+func (a *app) clientOptions(account string) uptimerobot.Options {
+	return uptimerobot.Options{
+		BaseURL: a.apiURL,
+		Timeout: a.apiTimeout,
+		Proxy:   a.proxy,
+		Workers: a.apiWorkers,
+		Retry:   a.apiRetry,
+		Breaker: a.apiBreaker,
+		Logger:  a.logger.With().Str("account", account).Logger(),
+
+		MetricsPrefix: a.metricsPrefix,
+	}
+}
 
-		resp, err := http.PostForm("https://api.uptimerobot.com/v2/getMonitors", data)
-		if err != nil {
-			a.logger.Error().Err(err).Msg("failed to fetch monitors")
-			continue
-		}
+// collectorOptions returns the options of the collectors, which query the API
+// on each collection when onDemand is set
+func (a *app) collectorOptions(onDemand bool) collector.Options {
+	return collector.Options{
+		OnDemand:                onDemand,
+		Filters:                 a.filters,
+		Overrides:               a.overrides,
+		SkipPaused:              a.skipPaused,
+		MaxFailedFetches:        a.maxFailed,
+		ResponseTimeWindow:      a.rtWindow,
+		LegacyAccountDetails:    a.legacyAccount,
+		RedactAccountPII:        a.redactPII,
+		LegacyMonitorLabels:     a.legacyLabels,
+		LowChurnLabels:          a.lowChurn,
+		LegacyResponseTimeUnits: a.legacyRTUnits,
+		ResponseTimeTimestamps:  a.rtTimestamps,
+		ResponseTimeHistogram:   a.rtHistogram,
+		NameLabels:              a.nameLabels,
+		LabelMappings:           a.labelMappings,
+		Labels:                  a.labels,
+		MetricsPrefix:           a.metricsPrefix,
+	}
+}
 
-		body, err := ioutil.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			a.logger.Error().Err(err).Msg("cannot parse response body")
-			continue
+// registerer returns a registerer adding the -const-label labels to the
+// metrics of the collectors registered with reg
+func (a *app) registerer(reg prometheus.Registerer) prometheus.Registerer {
+	return prometheus.WrapRegistererWith(prometheus.Labels(a.constLabels), reg)
+}
+
+// register registers the collectors with reg, adding the -const-label labels
+// to their metrics. They are collected one after the other, so that the
+// metrics of a client registered after its collector reflect the API calls
+// made by an on-demand collection. It fails if a constant label clashes with
+// the labels of a metric.
+func (a *app) register(reg prometheus.Registerer, collectors ...prometheus.Collector) error {
+	if err := a.registerer(reg).Register(inOrder(collectors)); err != nil {
+		return fmt.Errorf("cannot register metrics: %w", err)
+	}
+	return nil
+}
+
+// inOrder collects its collectors one after the other, while a registry
+// collects its collectors concurrently
+type inOrder []prometheus.Collector
+
+// Describe implements prometheus.Collector
+func (cs inOrder) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range cs {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (cs inOrder) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range cs {
+		c.Collect(ch)
+	}
+}
+
+// constLabels are the labels given with -const-label and
+// -push.grouping-label
+type constLabels prometheus.Labels
+
+func (l *constLabels) String() string {
+	pairs := make([]string, 0, len(*l))
+	for name, value := range *l {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set parses comma-separated name=value labels
+func (l *constLabels) Set(s string) error {
+	for _, label := range strings.Split(s, ",") {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("%q is not a name=value label", label)
+		}
+		name := parts[0]
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+			return fmt.Errorf("invalid label name %q", name)
 		}
+		if *l == nil {
+			*l = make(constLabels)
+		}
+		if _, ok := (*l)[name]; ok {
+			return fmt.Errorf("label %q given twice", name)
+		}
+		(*l)[name] = parts[1]
+	}
+	return nil
+}
 
-		var activeMonitors MonitorsData
-		if err := json.Unmarshal(body, &activeMonitors); err != nil {
-			a.logger.Error().Err(err).Msg("cannot parse JSON")
-			continue
+// keyValues are the key=value pairs given with the OTLP flags
+type keyValues map[string]string
+
+func (kv *keyValues) String() string {
+	pairs := make([]string, 0, len(*kv))
+	for key, value := range *kv {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set parses a key=value pair, or comma-separated ones as given by the env
+// variable of the flag
+func (kv *keyValues) Set(s string) error {
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" {
+			return fmt.Errorf("%q is not a key=value pair", pair)
 		}
+		if *kv == nil {
+			*kv = make(keyValues)
+		}
+		(*kv)[key] = strings.TrimSpace(parts[1])
+	}
+	return nil
+}
 
-		// compare currently active monitors to the one seen at the previous
-		// loop
-		for _, old := range previousMonitors.Monitors {
-			if !isMonitorStillActive(old, activeMonitors) {
-				// monitor 'old' not active anymore, let's try to remove its metrics
-				if monitorsStatus.DeleteLabelValues(old.URL, old.FriendlyName, strconv.Itoa(old.Interval)) {
-					a.logger.Debug().Msgf("monitor %s does not exist anymore, and its monitor_status metric has been deleted", old.FriendlyName)
-				} else {
-					a.logger.Warn().Msgf("monitor %s does not exist anymore, but its monitor_status could not have been deleted", old.FriendlyName)
-				}
-
-				if responseTime.DeleteLabelValues(old.URL, old.FriendlyName, strconv.Itoa(old.Type)) {
-					a.logger.Debug().Msgf("monitor %s does not exist anymore, and its response_time metric has been deleted", old.FriendlyName)
-				} else {
-					a.logger.Warn().Msgf("monitor %s does not exist anymore, but its response_time could not have been deleted", old.FriendlyName)
-				}
-			}
+// listenAddresses are the addresses given with -web.listen-address
+type listenAddresses []string
+
+func (l *listenAddresses) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set parses a host:port address, or comma-separated ones as given by the env
+// variable of the flag
+func (l *listenAddresses) Set(s string) error {
+	for _, addr := range strings.Split(s, ",") {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return err
 		}
+		*l = append(*l, addr)
+	}
+	return nil
+}
 
-		// update the metrics of the currently active monitors
-		for _, m := range activeMonitors.Monitors {
-			a.logger.Debug().Msgf("updating monitors metrics for %s: %f (rtt count %d)", m.FriendlyName, float64(m.Status), len(m.ResponseTimes))
-			monitorsStatus.WithLabelValues(m.URL, m.FriendlyName, strconv.Itoa(m.Interval)).Set(float64(m.Status))
-			if len(m.ResponseTimes) > 0 {
-				responseTime.WithLabelValues(m.URL, m.FriendlyName, strconv.Itoa(m.Type)).Set(float64(m.ResponseTimes[0].Value))
-			}
+// monitorTags returns the tags given with -tags
+func (a *app) monitorTags() []string {
+	if a.tagsFlag == "" {
+		return nil
+	}
+	tags := strings.Split(a.tagsFlag, ",")
+	for i, tag := range tags {
+		tags[i] = strings.TrimSpace(tag)
+	}
+	return tags
+}
 
-			// save the currently active monitors
-			previousMonitors = activeMonitors
+// parseMonitorIDs parses a comma-separated list of monitor IDs
+func parseMonitorIDs(list string) ([]int, error) {
+	var ids []int
+	for _, field := range strings.Split(list, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid monitor ID %q", field)
 		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseProxyURL parses the URL of a proxy supported by net/http
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected http, https or socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing proxy host in %q", raw)
 	}
+	return u, nil
 }
 
-func isMonitorStillActive(monitor Monitor, active MonitorsData) bool {
-	for _, active := range active.Monitors {
-		if active.FriendlyName == monitor.FriendlyName {
-			return true
+// checkAPIKey validates the API key of client against the API. An error is
+// returned when the key is rejected, unless -no-fail-on-auth-error is set.
+// Other errors are only logged as the API may just be temporarily unreachable.
+func (a *app) checkAPIKey(client *uptimerobot.Client, account string) error {
+	err := client.CheckAPIKey(a.ctx)
+	switch {
+	case err == nil:
+		if client.MonitorScoped() {
+			a.logger.Warn().Str("account", account).Msg("API key is restricted to monitors, account details will not be exported")
 		}
+	case uptimerobot.IsAuthError(err) && !a.noFailOnAuth:
+		return fmt.Errorf("API key of account %s rejected by Uptime Robot: %w", account, err)
+	default:
+		a.logger.Error().Err(err).Str("account", account).Msg("cannot validate API key")
 	}
-	return false
+	return nil
 }
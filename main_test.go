@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eze-kiel/uptimerobot-exporter/collector"
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+func TestOnDemandAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"stat":"fail","error":{"type":"invalid_parameter","parameter_name":"api_key","message":"api_key is wrong"}}`))
+	}))
+	defer srv.Close()
+
+	client := uptimerobot.New("key", uptimerobot.Options{BaseURL: srv.URL, Logger: zerolog.Nop()})
+	c := collector.New(context.Background(), client, zerolog.Nop(), collector.Options{OnDemand: true})
+	registry := prometheus.NewRegistry()
+	a := &app{}
+	if err := a.register(registry, c, client); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+	}
+	want := `uptimerobot_api_up{error_type="invalid_parameter"} 0`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("%s not found in:\n%s", want, w.Body)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/collector"
+	"github.com/eze-kiel/uptimerobot-exporter/sink"
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// runOnce fetches the Uptime Robot data a single time and writes the metrics
+// to stdout in the Prometheus text format, or pushes them to the Pushgateway
+// of -push.gateway-url, or writes them to the textfile of -textfile.directory.
+// The metrics that could be gathered are written even when an error is
+// returned.
+func (a *app) runOnce() error {
+	if a.pushGatewayURL != "" && a.textfileDirectory != "" {
+		return errors.New("-push.gateway-url and -textfile.directory are mutually exclusive with the check command")
+	}
+	registry, c, err := a.onceRegistry()
+	if err != nil {
+		return err
+	}
+	if a.pushGatewayURL != "" {
+		if err := a.push(registry); err != nil {
+			return fmt.Errorf("cannot push the metrics: %w", err)
+		}
+		return failedFetches(c)
+	}
+	families, gatherErr := registry.Gather()
+	if gatherErr == nil {
+		gatherErr = failedFetches(c)
+	}
+
+	if a.textfileDirectory != "" {
+		s, err := sink.NewTextfile(a.textfileDirectory)
+		if err != nil {
+			return err
+		}
+		if err := s.Write(a.ctx, a.selectSinkMetrics(families), time.Now()); err != nil {
+			return fmt.Errorf("cannot write %s: %w", s.Path(), err)
+		}
+		return gatherErr
+	}
+
+	enc := expfmt.NewEncoder(os.Stdout, expfmt.FmtText)
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	return gatherErr
+}
+
+// onceRegistry returns a registry fetching the Uptime Robot data of the main
+// account each time it is gathered, and the collector fetching it
+func (a *app) onceRegistry() (*prometheus.Registry, *collector.Collector, error) {
+	cfg, err := a.readConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := a.applyMonitorConfig(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	apiKey, err := a.resolveAPIKey(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if apiKey == "" {
+		return nil, nil, errors.New("missing Uptime Robot API key, use -api-key, -api-key-file or UPTIMEROBOT_API_KEY env variable")
+	}
+
+	client := uptimerobot.New(apiKey, a.clientOptions("default"))
+	if err := a.checkAPIKey(client, "default"); err != nil {
+		return nil, nil, err
+	}
+
+	registry := prometheus.NewRegistry()
+	c := collector.New(a.ctx, client, a.logger, a.collectorOptions(true))
+	if err := a.register(registry, c, client); err != nil {
+		return nil, nil, err
+	}
+	return registry, c, nil
+}
+
+// failedFetches returns an error listing the fetches of c that failed, if
+// any, as they do not fail the gathering
+func failedFetches(c *collector.Collector) error {
+	var failed []string
+	for name, fetch := range c.Status().Fetches {
+		if !fetch.Success {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, fetch.Error))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	return fmt.Errorf("cannot fetch the %s", strings.Join(failed, ", "))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeHandler serves the metrics of the account named by the api_key_name
+// query parameter, blackbox exporter style. The API is always queried on
+// demand.
+func (a *app) probeHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("api_key_name")
+	if name == "" {
+		http.Error(w, "api_key_name parameter is missing", http.StatusBadRequest)
+		return
+	}
+	a.serveAccount(w, r, name)
+}
+
+// accountHandler serves the metrics of the account named by the path
+// /metrics/<name>, so that each tenant can be given its own scrape target
+// exposing only its series. The API is always queried on demand.
+func (a *app) accountHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/metrics/")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	a.serveAccount(w, r, name)
+}
+
+// serveAccount serves the metrics of the account named name
+func (a *app) serveAccount(w http.ResponseWriter, r *http.Request, name string) {
+	a.mu.RLock()
+	acc, ok := a.accounts[name]
+	a.mu.RUnlock()
+	if !ok {
+		http.Error(w, "unknown account "+name, http.StatusNotFound)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := a.register(registry, acc.collector, acc.client); err != nil {
+		a.logger.Error().Err(err).Str("account", name).Msg("cannot register the account metrics")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true}).ServeHTTP(w, r)
+}
+
+// lookupAccount returns the main account, or the account named by the account
+// query parameter of r. When there is none, it answers 404 and returns nil.
+func (a *app) lookupAccount(w http.ResponseWriter, r *http.Request) *account {
+	name := r.URL.Query().Get("account")
+
+	a.mu.RLock()
+	acc, ok := a.accounts[name]
+	if name == "" {
+		acc, ok = &account{client: a.client, collector: a.collector}, a.client != nil
+	}
+	a.mu.RUnlock()
+	switch {
+	case ok:
+		return acc
+	case name == "":
+		http.Error(w, "no main account, use the account query parameter", http.StatusNotFound)
+	default:
+		http.Error(w, "unknown account "+name, http.StatusNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// push pushes the metrics gathered by g to the Pushgateway of
+// -push.gateway-url, replacing the ones previously pushed with the same job
+// and grouping labels
+func (a *app) push(g prometheus.Gatherer) error {
+	// the credentials of the URL, if any, are sent with basic authentication
+	pusher := push.New(a.pushGatewayURL, a.pushJob).Gatherer(g)
+	for name, value := range a.pushGrouping {
+		pusher = pusher.Grouping(name, value)
+	}
+	if err := pusher.Push(); err != nil {
+		return err
+	}
+	a.logger.Debug().Msg("metrics pushed to the Pushgateway")
+	return nil
+}
+
+// pushEvery pushes the metrics gathered by g to the Pushgateway right away and
+// then every interval, until ctx is done
+func (a *app) pushEvery(ctx context.Context, g prometheus.Gatherer, interval time.Duration) {
+	a.logger.Info().Msgf("pushing the metrics to the Pushgateway every %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.push(g); err != nil {
+			a.logger.Error().Err(err).Msg("cannot push the metrics to the Pushgateway")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeLimiter limits the rate of the scrapes of each client, identified by
+// its IP, with a token bucket per client, so that a misconfigured scraper
+// cannot make the exporter call the API too often in on-demand mode
+type scrapeLimiter struct {
+	rate     float64
+	burst    float64
+	rejected prometheus.Counter
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// bucket holds the scrapes a client can make right away, as of last
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newScrapeLimiter returns a limiter allowing rate scrapes per second to each
+// client, with bursts of up to burst scrapes
+func newScrapeLimiter(rate float64, burst int, rejected prometheus.Counter) *scrapeLimiter {
+	return &scrapeLimiter{
+		rate:     rate,
+		burst:    float64(burst),
+		rejected: rejected,
+		buckets:  make(map[string]*bucket),
+		swept:    time.Now(),
+	}
+}
+
+// limit answers 429 to the clients exceeding their rate, and serves the
+// requests of the others with h
+func (l *scrapeLimiter) limit(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			// Unix domain socket
+			client = r.RemoteAddr
+		}
+		if wait := l.take(client, time.Now()); wait > 0 {
+			l.rejected.Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "scraped too often", http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// take takes a token from the bucket of client at now. It returns 0 if there
+// was one, and the time until there is one otherwise.
+func (l *scrapeLimiter) take(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// sweep forgets the clients whose bucket is full again, at most once per
+// refill period, so that the buckets of gone clients do not pile up
+func (l *scrapeLimiter) sweep(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.swept) < refill {
+		return
+	}
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, client)
+		}
+	}
+	l.swept = now
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newTestLimiter(rate float64, burst int) *scrapeLimiter {
+	return newScrapeLimiter(rate, burst, prometheus.NewCounter(prometheus.CounterOpts{Name: "rejected"}))
+}
+
+func TestScrapeLimiterTake(t *testing.T) {
+	type scrape struct {
+		client string
+		// at is the time of the scrape, after the creation of the limiter
+		at   time.Duration
+		want time.Duration
+	}
+	tests := []struct {
+		name    string
+		rate    float64
+		burst   int
+		scrapes []scrape
+	}{
+		{
+			name:  "burst",
+			rate:  1,
+			burst: 2,
+			scrapes: []scrape{
+				{"a", 0, 0},
+				{"a", 0, 0},
+				{"a", 0, time.Second},
+				{"a", 250 * time.Millisecond, 750 * time.Millisecond},
+			},
+		},
+		{
+			name:  "refill",
+			rate:  0.5,
+			burst: 1,
+			scrapes: []scrape{
+				{"a", 0, 0},
+				{"a", time.Second, time.Second},
+				{"a", 2 * time.Second, 0},
+				{"a", 3 * time.Second, time.Second},
+			},
+		},
+		{
+			name:  "refill up to the burst",
+			rate:  1,
+			burst: 2,
+			scrapes: []scrape{
+				{"a", 0, 0},
+				{"a", 0, 0},
+				{"a", time.Hour, 0},
+				{"a", time.Hour, 0},
+				{"a", time.Hour, time.Second},
+			},
+		},
+		{
+			name:  "clients",
+			rate:  1,
+			burst: 1,
+			scrapes: []scrape{
+				{"a", 0, 0},
+				{"b", 0, 0},
+				{"a", 0, time.Second},
+				{"@", 0, 0},
+				{"@", 0, time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLimiter(tt.rate, tt.burst)
+			start := l.swept
+			for i, s := range tt.scrapes {
+				if got := l.take(s.client, start.Add(s.at)); got != s.want {
+					t.Errorf("scrape %d of %s at %s: got a wait of %s, want %s", i, s.client, s.at, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestScrapeLimiterSweep(t *testing.T) {
+	tests := []struct {
+		name string
+		// last is the time of the last scrape of each client and at the
+		// time of the sweep, both after the creation of the limiter
+		last map[string]time.Duration
+		at   time.Duration
+		want []string
+	}{
+		{
+			name: "too early",
+			last: map[string]time.Duration{"a": 0, "b": time.Second},
+			at:   time.Second,
+			want: []string{"a", "b"},
+		},
+		{
+			name: "full buckets",
+			last: map[string]time.Duration{"a": 0, "b": time.Second},
+			at:   2 * time.Second,
+			want: []string{"b"},
+		},
+		{
+			name: "all full",
+			last: map[string]time.Duration{"a": 0, "b": time.Second},
+			at:   time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// buckets are full again 2s after their last scrape
+			l := newTestLimiter(1, 2)
+			start := l.swept
+			for client, last := range tt.last {
+				l.buckets[client] = &bucket{tokens: 1, last: start.Add(last)}
+			}
+			l.sweep(start.Add(tt.at))
+			if len(l.buckets) != len(tt.want) {
+				t.Errorf("got %d buckets, want %v", len(l.buckets), tt.want)
+			}
+			for _, client := range tt.want {
+				if _, ok := l.buckets[client]; !ok {
+					t.Errorf("bucket of %s dropped", client)
+				}
+			}
+		})
+	}
+}
+
+func TestScrapeLimiterRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		rate       float64
+		remoteAddr string
+		retryAfter string
+	}{
+		{"TCP client", 1, "192.0.2.1:1234", "1"},
+		{"rounded up", 0.4, "192.0.2.1:1234", "3"},
+		{"Unix socket client", 1, "@", "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLimiter(tt.rate, 1)
+			h := l.limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			var codes []int
+			for i := 0; i < 2; i++ {
+				r := httptest.NewRequest("GET", "/metrics", nil)
+				r.RemoteAddr = tt.remoteAddr
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				codes = append(codes, w.Code)
+				if w.Code == http.StatusTooManyRequests {
+					if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+						t.Errorf("got Retry-After %q, want %q", got, tt.retryAfter)
+					}
+				}
+			}
+			if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+				t.Errorf("got statuses %v, want 200 then 429", codes)
+			}
+		})
+	}
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/collector"
+	"github.com/eze-kiel/uptimerobot-exporter/config"
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+	"github.com/eze-kiel/uptimerobot-exporter/uptimerobot"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// loadConfig applies the configuration file on top of the command line flags.
+// It is called at startup and on each reload: the log level, the main API
+// key, the polling intervals, the monitor filters and the accounts can change
+// without restarting. The new configuration is validated and its API keys are
+// checked before any of it is applied, so that a failed reload leaves the
+// current one in place, and without holding mu, so that the requests are not
+// blocked meanwhile.
+func (a *app) loadConfig() error {
+	a.keyMu.Lock()
+	defer a.keyMu.Unlock()
+
+	cfg, err := a.readConfig()
+	if err != nil {
+		return err
+	}
+
+	settings := a.resolver(cfg)
+	logLevel := settings.string("log-level", a.logLevel, cfg.LogLevel)
+	if err := logger.SetLevel(logLevel); err != nil {
+		a.logger.Error().Err(err).Msgf("cannot parse level %s, keeping the current one", logLevel)
+	}
+
+	interval := settings.duration("interval", time.Duration(a.scrapeInterval)*time.Second, cfg.Interval)
+	intervals := collector.Intervals{
+		Account:  settings.duration("account-interval", a.accountEvery, cfg.AccountInterval),
+		Monitors: settings.duration("monitors-interval", a.monitorsEvery, cfg.MonitorsInterval),
+		Jitter:   settings.duration("jitter", a.jitter, cfg.Jitter),
+	}
+	// the account and monitors intervals default to the general one
+	if intervals.Account <= 0 {
+		intervals.Account = interval
+	}
+	if intervals.Monitors <= 0 {
+		intervals.Monitors = interval
+	}
+
+	apiKey, err := a.resolveAPIKey(cfg)
+	if err != nil {
+		return err
+	}
+	bearerToken, err := a.resolveBearerToken()
+	if err != nil {
+		return err
+	}
+
+	labels, err := a.monitorLabels(cfg)
+	if err != nil {
+		return err
+	}
+	overrides, err := monitorOverrides(cfg)
+	if err != nil {
+		return err
+	}
+	filters, err := a.monitorFilters(cfg)
+	if err != nil {
+		return err
+	}
+	if a.webConfigFile != "" && len(cfg.BasicAuthUsers) > 0 {
+		return errors.New("basic_auth_users cannot be combined with -web.config.file, declare the users in the web configuration file")
+	}
+	if err := a.checkDebugAPIAuth(cfg, bearerToken); err != nil {
+		return err
+	}
+
+	a.mu.RLock()
+	current, mainClient := a.accounts, a.client
+	a.mu.RUnlock()
+
+	// the clients of the new accounts, whose collectors are created once the
+	// monitor labels are applied
+	clients := make(map[string]*uptimerobot.Client)
+	for _, acc := range cfg.Accounts {
+		if existing, ok := current[acc.Name]; ok && existing.client.APIKey() == acc.APIKey {
+			continue
+		}
+		client := uptimerobot.New(acc.APIKey, a.clientOptions(acc.Name))
+		if err := a.checkAPIKey(client, acc.Name); err != nil {
+			return err
+		}
+		clients[acc.Name] = client
+	}
+
+	// newClient is the client of the main API key at startup, and checked
+	// the one of a changed key
+	var newClient, checked *uptimerobot.Client
+	switch {
+	case apiKey == "" && mainClient != nil:
+		return errors.New("the main API key cannot be removed without restarting")
+	case apiKey == "" && len(cfg.Accounts) == 0:
+		return errors.New("missing Uptime Robot API key, use -api-key, -api-key-file or UPTIMEROBOT_API_KEY env variable")
+	case apiKey == "":
+	case mainClient == nil:
+		a.logger.Info().Msg("API key found")
+		newClient = uptimerobot.New(apiKey, a.clientOptions("default"))
+		if err := a.checkAPIKey(newClient, "default"); err != nil {
+			return err
+		}
+	case mainClient.APIKey() != apiKey:
+		if checked, err = a.checkNewAPIKey(apiKey); err != nil {
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.setMonitorLabels(labels)
+	a.setMonitorOverrides(overrides)
+	a.setMonitorFilters(filters)
+	a.authUsers = cfg.BasicAuthUsers
+	a.bearerToken = bearerToken
+
+	accounts := make(map[string]*account, len(cfg.Accounts))
+	for _, acc := range cfg.Accounts {
+		client, ok := clients[acc.Name]
+		if !ok {
+			accounts[acc.Name] = current[acc.Name]
+			continue
+		}
+		logger := a.logger.With().Str("account", acc.Name).Logger()
+		accounts[acc.Name] = &account{
+			client:    client,
+			collector: collector.New(a.ctx, client, logger, a.collectorOptions(true)),
+		}
+	}
+	a.accounts = accounts
+	if a.configFile != "" {
+		a.logger.Info().Msgf("%d accounts loaded from %s", len(accounts), a.configFile)
+	}
+
+	switch {
+	case apiKey == "":
+		a.logger.Info().Msg("no API key provided, Uptime Robot metrics are only available on /probe")
+		return nil
+	case newClient != nil:
+		a.client = newClient
+		a.collector = collector.New(a.ctx, a.client, a.logger, a.collectorOptions(a.onDemand))
+		if err := a.register(prometheus.DefaultRegisterer, a.collector, a.client); err != nil {
+			return err
+		}
+	case checked != nil:
+		a.logger.Info().Msg("API key changed")
+		a.client.UseAPIKeyOf(checked)
+		a.keyReloads.Inc()
+	}
+
+	if !a.onDemand && intervals != a.intervals {
+		if a.stopPolling != nil {
+			a.stopPolling()
+		}
+		a.logger.Info().Msgf("starting fetch routines, account details every %s and monitors every %s (jitter %s)", intervals.Account, intervals.Monitors, intervals.Jitter)
+		var ctx context.Context
+		ctx, a.stopPolling = context.WithCancel(a.ctx)
+		a.collector.Run(ctx, intervals)
+		a.intervals = intervals
+	}
+	return nil
+}
+
+// checkNewAPIKey checks a new main API key with a client of its own, so that
+// the key is only installed once Uptime Robot accepted it. The returned client
+// holds the key along with its detected scope.
+func (a *app) checkNewAPIKey(apiKey string) (*uptimerobot.Client, error) {
+	client := uptimerobot.New(apiKey, a.clientOptions("default"))
+	if err := a.checkAPIKey(client, "default"); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// monitorLabels are the validated labels of the per-monitor metrics
+type monitorLabels struct {
+	nameLabels []*regexp.Regexp
+	labels     []string
+	mappings   []collector.LabelMapping
+}
+
+// monitorLabels returns the labels of the per-monitor metrics: the rules
+// extracting labels from the friendly names, the monitor attributes used as
+// labels and the labels set by the monitor labels file and by the monitors
+// section
+func (a *app) monitorLabels(cfg *config.Config) (monitorLabels, error) {
+	var l monitorLabels
+	l.nameLabels = make([]*regexp.Regexp, len(cfg.FriendlyNameLabels))
+	for i, expr := range cfg.FriendlyNameLabels {
+		l.nameLabels[i] = regexp.MustCompile(expr)
+	}
+	if err := collector.CheckNameLabels(l.nameLabels); err != nil {
+		return l, fmt.Errorf("invalid friendly_name_labels: %w", err)
+	}
+
+	names := a.resolver(cfg).list("labels", a.labelsFlag, cfg.Labels)
+	if len(names) > 0 {
+		var err error
+		if l.labels, err = collector.ParseLabels(names); err != nil {
+			return l, fmt.Errorf("invalid labels: %w", err)
+		}
+	}
+
+	if a.monitorLabelsFile != "" {
+		var err error
+		if l.mappings, err = readLabelMappings(a.monitorLabelsFile); err != nil {
+			return l, err
+		}
+	}
+	// the labels of the monitor overrides come last to take precedence
+	ids := make([]int, 0, len(cfg.Monitors))
+	for id := range cfg.Monitors {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if labels := cfg.Monitors[id].Labels; len(labels) > 0 {
+			l.mappings = append(l.mappings, collector.LabelMapping{ID: id, Labels: labels})
+		}
+	}
+
+	// the mappings are checked against the rules in effect, which cannot
+	// change once the collectors are created
+	a.mu.RLock()
+	nameLabels := a.nameLabels
+	if a.client == nil && a.accounts == nil {
+		nameLabels = l.nameLabels
+	}
+	a.mu.RUnlock()
+	if err := collector.CheckLabelMappings(l.mappings, nameLabels); err != nil {
+		return l, fmt.Errorf("invalid monitor labels: %w", err)
+	}
+	return l, nil
+}
+
+// setMonitorLabels sets the labels of the per-monitor metrics. The labels of
+// the metrics cannot change once the collectors are created, so changes made
+// afterwards are ignored until the next restart, except for the values of the
+// labels set per monitor.
+func (a *app) setMonitorLabels(l monitorLabels) {
+	first := a.client == nil && a.accounts == nil
+	if first {
+		a.nameLabels = l.nameLabels
+		a.labels = l.labels
+		a.labelMappings = collector.NewLabelMappings(l.mappings)
+		return
+	}
+	if !sameRules(a.nameLabels, l.nameLabels) || strings.Join(a.labels, ",") != strings.Join(l.labels, ",") {
+		a.logger.Warn().Msg("changes to the labels of the monitor metrics are only applied on restart")
+	}
+	previous := a.labelMappings.Names()
+	a.labelMappings.Set(l.mappings)
+	if strings.Join(previous, ",") != strings.Join(a.labelMappings.Names(), ",") {
+		a.logger.Warn().Msg("monitor labels added or removed are only applied on restart")
+	}
+}
+
+// monitorOverrides returns the validated overrides of the monitors defined in
+// the configuration file
+func monitorOverrides(cfg *config.Config) (map[int]collector.MonitorOverride, error) {
+	overrides := make(map[int]collector.MonitorOverride, len(cfg.Monitors))
+	for id, override := range cfg.Monitors {
+		overrides[id] = collector.MonitorOverride{
+			Disabled:     override.Disabled,
+			FriendlyName: override.FriendlyName,
+			Metrics:      override.Metrics,
+		}
+	}
+	if err := collector.CheckMonitorOverrides(overrides); err != nil {
+		return nil, fmt.Errorf("invalid monitors: %w", err)
+	}
+	return overrides, nil
+}
+
+// setMonitorOverrides sets the overrides of the monitors
+func (a *app) setMonitorOverrides(overrides map[int]collector.MonitorOverride) {
+	if a.overrides == nil {
+		a.overrides = collector.NewMonitorOverrides(overrides)
+	} else {
+		a.overrides.Set(overrides)
+	}
+}
+
+// monitorFilters returns the filters selecting the exported monitors, given by
+// the flags or by the configuration file
+func (a *app) monitorFilters(cfg *config.Config) (collector.Filters, error) {
+	r := a.resolver(cfg)
+	f := collector.Filters{
+		IDs:      a.monitorIDs,
+		Types:    a.monitorTypes,
+		Statuses: a.monitorStatuses,
+		Search:   r.string("monitor-search", a.monitorSearch, cfg.MonitorSearch),
+		Tags:     a.monitorTags(),
+		Include:  a.includeMonitors,
+		Exclude:  a.excludeMonitors,
+	}
+	if r.fromConfig("monitor-ids", len(cfg.MonitorIDs) > 0) {
+		f.IDs = cfg.MonitorIDs
+	}
+	if r.fromConfig("monitor-types", len(cfg.MonitorTypes) > 0) {
+		var err error
+		if f.Types, err = collector.ParseMonitorTypes(cfg.MonitorTypes); err != nil {
+			return f, fmt.Errorf("invalid monitor_types: %w", err)
+		}
+	}
+	if r.fromConfig("monitor-statuses", len(cfg.MonitorStatuses) > 0) {
+		var err error
+		if f.Statuses, err = collector.ParseMonitorStatuses(cfg.MonitorStatuses); err != nil {
+			return f, fmt.Errorf("invalid monitor_statuses: %w", err)
+		}
+	}
+	if r.fromConfig("tags", len(cfg.Tags) > 0) {
+		f.Tags = cfg.Tags
+	}
+	// the expressions are checked when the configuration file is loaded
+	if r.fromConfig("include-monitors", cfg.IncludeMonitors != "") {
+		f.Include = regexp.MustCompile(cfg.IncludeMonitors)
+	}
+	if r.fromConfig("exclude-monitors", cfg.ExcludeMonitors != "") {
+		f.Exclude = regexp.MustCompile(cfg.ExcludeMonitors)
+	}
+	return f, nil
+}
+
+// setMonitorFilters sets the filters selecting the exported monitors
+func (a *app) setMonitorFilters(filters collector.Filters) {
+	if a.filters == nil {
+		a.filters = collector.NewMonitorFilters(filters)
+	} else {
+		a.filters.Set(filters)
+	}
+}
+
+// applyMonitorConfig validates and sets the monitor labels, overrides and
+// filters of cfg, for the commands that do not reload
+func (a *app) applyMonitorConfig(cfg *config.Config) error {
+	labels, err := a.monitorLabels(cfg)
+	if err != nil {
+		return err
+	}
+	overrides, err := monitorOverrides(cfg)
+	if err != nil {
+		return err
+	}
+	filters, err := a.monitorFilters(cfg)
+	if err != nil {
+		return err
+	}
+	a.setMonitorLabels(labels)
+	a.setMonitorOverrides(overrides)
+	a.setMonitorFilters(filters)
+	return nil
+}
+
+// readLabelMappings reads the monitor labels file located at path
+func readLabelMappings(path string) ([]collector.LabelMapping, error) {
+	file, err := config.LoadMonitorLabels(path)
+	if err != nil {
+		return nil, err
+	}
+	mappings := make([]collector.LabelMapping, len(file.Monitors))
+	for i, entry := range file.Monitors {
+		mappings[i] = collector.LabelMapping{ID: entry.ID, Labels: entry.Labels}
+		if entry.URL != "" {
+			mappings[i].URL = regexp.MustCompile(entry.URL)
+		}
+	}
+	return mappings, nil
+}
+
+// sameRules reports whether a and b hold the same regular expressions
+func sameRules(a, b []*regexp.Regexp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].String() != b[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+// readConfig reads the configuration file, if any
+func (a *app) readConfig() (*config.Config, error) {
+	if a.configFile == "" {
+		return &config.Config{}, nil
+	}
+	return config.Load(a.configFile)
+}
+
+// resolveAPIKey returns the main API key, fetched from the secret store when
+// there is one. Otherwise it is taken from -api-key or its env variables,
+// then from -api-key-file and then from the configuration file.
+func (a *app) resolveAPIKey(cfg *config.Config) (string, error) {
+	if a.keySource != nil {
+		return a.fetchAPIKey()
+	}
+	if a.apiKey != "" {
+		return a.apiKey, nil
+	}
+	if a.apiKeyFile != "" {
+		return a.readAPIKeyFile()
+	}
+	return cfg.APIKey, nil
+}
+
+// readAPIKeyFile returns the API key held by -api-key-file
+func (a *app) readAPIKeyFile() (string, error) {
+	content, err := ioutil.ReadFile(a.apiKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("cannot read API key: %w", err)
+	}
+	apiKey := strings.TrimSpace(string(content))
+	if apiKey == "" {
+		return "", fmt.Errorf("API key file %s is empty", a.apiKeyFile)
+	}
+	return apiKey, nil
+}
+
+// reload reloads the configuration and logs the outcome
+func (a *app) reload() error {
+	a.logger.Info().Msg("reloading configuration")
+	if err := a.loadConfig(); err != nil {
+		a.logger.Error().Err(err).Msg("cannot reload configuration")
+		return err
+	}
+	a.logger.Info().Msg("configuration reloaded")
+	return nil
+}
+
+// reloadOnSIGHUP reloads the configuration each time SIGHUP is received
+func (a *app) reloadOnSIGHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for {
+		select {
+		case <-a.ctx.Done():
+			signal.Stop(hup)
+			return
+		case <-hup:
+			a.reload()
+		}
+	}
+}
+
+// reloadHandler reloads the configuration on POST or PUT /-/reload
+func (a *app) reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		http.Error(w, "this endpoint requires a POST or PUT request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := a.reload(); err != nil {
+		http.Error(w, "failed to reload config: "+err.Error(), http.StatusInternalServerError)
+	}
+}
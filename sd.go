@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eze-kiel/uptimerobot-exporter/collector"
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+)
+
+// sdMetaPrefix prefixes the labels of the discovered targets, which are only
+// kept by Prometheus through relabeling
+const sdMetaPrefix = "__meta_uptimerobot_"
+
+// sdTargetGroup is a target group of the Prometheus HTTP service discovery
+type sdTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// sdHandler serves the monitors of the main account, or of the account named
+// by the account query parameter, as targets of the Prometheus HTTP service
+// discovery, so that blackbox exporter jobs probe the URLs checked by Uptime
+// Robot. The heartbeat monitors, which have no target, are left out.
+func (a *app) sdHandler(w http.ResponseWriter, r *http.Request) {
+	acc := a.lookupAccount(w, r)
+	if acc == nil {
+		return
+	}
+
+	states, err := acc.collector.Monitors(r.Context())
+	if err != nil {
+		http.Error(w, logger.Redact(err.Error()), http.StatusServiceUnavailable)
+		return
+	}
+	name := r.URL.Query().Get("account")
+	groups := make([]sdTargetGroup, 0, len(states))
+	for _, state := range states {
+		if state.URL == "" || state.Type == "heartbeat" {
+			continue
+		}
+		groups = append(groups, newSDTargetGroup(state, name))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		a.logger.Error().Err(err).Msg("cannot write /sd answer")
+	}
+}
+
+// newSDTargetGroup returns the target group of a monitor. Its tags are given
+// both as a comma-separated list, surrounded by commas so that relabeling can
+// match ",tag,", and as a label per tag set to true.
+func newSDTargetGroup(state collector.MonitorState, account string) sdTargetGroup {
+	labels := map[string]string{
+		sdMetaPrefix + "monitor_id":    strconv.Itoa(state.ID),
+		sdMetaPrefix + "friendly_name": state.FriendlyName,
+		sdMetaPrefix + "type":          state.Type,
+		sdMetaPrefix + "status":        state.Status,
+		sdMetaPrefix + "tags":          "",
+	}
+	if account != "" {
+		labels[sdMetaPrefix+"account"] = account
+	}
+	if len(state.Tags) > 0 {
+		labels[sdMetaPrefix+"tags"] = "," + strings.Join(state.Tags, ",") + ","
+	}
+	for _, tag := range state.Tags {
+		labels[sdMetaPrefix+"tag_"+labelNameSafe(tag)] = "true"
+	}
+	return sdTargetGroup{Targets: []string{state.URL}, Labels: labels}
+}
+
+// labelNameSafe replaces the characters of s that are not allowed in label
+// names with underscores
+func labelNameSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/systemd"
+)
+
+// watchdogGrace is how late the polling loops may be, as a fetch can take a
+// while with retries and pagination, before the systemd watchdog is no longer
+// notified
+const watchdogGrace = 5 * time.Minute
+
+// notifySystemd tells systemd that the exporter is ready once it has metrics
+// to serve, and then notifies the systemd watchdog as long as the polling
+// loops are not stalled, until ctx is done. It does nothing when the exporter
+// is not run by systemd as a notify service.
+func (a *app) notifySystemd(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for !a.servingMetrics() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+	if !a.sdNotify(systemd.Ready) {
+		return
+	}
+
+	interval := systemd.WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	a.logger.Info().Msgf("notifying the systemd watchdog every %s", interval/2)
+	ticker.Reset(interval / 2)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		a.mu.RLock()
+		var stalled []string
+		if a.collector != nil {
+			stalled = a.collector.Stalled(watchdogGrace)
+		}
+		a.mu.RUnlock()
+		if len(stalled) > 0 {
+			a.logger.Error().Msgf("fetches of %s stalled, not notifying the systemd watchdog", strings.Join(stalled, ", "))
+			continue
+		}
+		a.sdNotify(systemd.Watchdog)
+	}
+}
+
+// servingMetrics reports whether the exporter has metrics to serve, i.e. if
+// the first fetch of the monitors succeeded when polling the API. In on-demand
+// mode, the API key has already been checked at startup.
+func (a *app) servingMetrics() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.collector == nil {
+		return true
+	}
+	status := a.collector.Status()
+	return status.Ready && !status.Failing
+}
+
+// sdNotify sends state to systemd, and reports whether it was sent
+func (a *app) sdNotify(state string) bool {
+	sent, err := systemd.Notify(state)
+	if err != nil {
+		a.logger.Warn().Err(err).Msgf("cannot notify systemd of %s", state)
+		return false
+	}
+	if sent {
+		a.logger.Debug().Msgf("notified systemd of %s", state)
+	}
+	return sent
+}
@@ -0,0 +1,130 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// Schemes of the sources of the API key stored in AWS
+const (
+	SchemeAWSSecretsManager = "aws-secretsmanager://"
+	SchemeAWSSSM            = "aws-ssm://"
+)
+
+// AWSSecretsManager reads the API key from an AWS Secrets Manager secret. The
+// credentials and the region are found the usual AWS way, so that the IAM role
+// of an ECS task or of an EKS service account is used.
+type AWSSecretsManager struct {
+	client *secretsmanager.SecretsManager
+	name   string
+	field  string
+}
+
+// AWSSSM reads the API key from an AWS Systems Manager Parameter Store
+// parameter, decrypting SecureString parameters
+type AWSSSM struct {
+	client *ssm.SSM
+	name   string
+}
+
+// Parse returns the source of the API key described by source:
+//   - aws-secretsmanager://name reads the secret name or ARN, the key being
+//     the whole secret string, or the field of a JSON secret given as
+//     aws-secretsmanager://name#field
+//   - aws-ssm://name reads the parameter name, such as
+//     aws-ssm:///uptimerobot/api-key
+func Parse(source string) (Source, error) {
+	switch {
+	case strings.HasPrefix(source, SchemeAWSSecretsManager):
+		name := strings.TrimPrefix(source, SchemeAWSSecretsManager)
+		var field string
+		if i := strings.LastIndex(name, "#"); i >= 0 {
+			name, field = name[:i], name[i+1:]
+		}
+		if name == "" {
+			return nil, errors.New("missing AWS Secrets Manager secret name")
+		}
+		sess, err := newAWSSession()
+		if err != nil {
+			return nil, err
+		}
+		return &AWSSecretsManager{client: secretsmanager.New(sess), name: name, field: field}, nil
+	case strings.HasPrefix(source, SchemeAWSSSM):
+		name := strings.TrimPrefix(source, SchemeAWSSSM)
+		if name == "" {
+			return nil, errors.New("missing AWS SSM parameter name")
+		}
+		sess, err := newAWSSession()
+		if err != nil {
+			return nil, err
+		}
+		return &AWSSSM{client: ssm.New(sess), name: name}, nil
+	default:
+		return nil, fmt.Errorf("unsupported API key source %q, expected %sname or %sname", source, SchemeAWSSecretsManager, SchemeAWSSSM)
+	}
+}
+
+// newAWSSession returns an AWS session configured by the environment, the
+// shared configuration files and the instance or task metadata
+func newAWSSession() (*session.Session, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot configure AWS session: %w", err)
+	}
+	return sess, nil
+}
+
+// APIKey implements Source
+func (s *AWSSecretsManager) APIKey(ctx context.Context) (string, error) {
+	out, err := s.client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(s.name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot read AWS secret %s: %w", s.name, err)
+	}
+	value := aws.StringValue(out.SecretString)
+
+	if s.field != "" {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &fields); err != nil {
+			return "", fmt.Errorf("AWS secret %s is not a JSON object: %w", s.name, err)
+		}
+		value, _ = fields[s.field].(string)
+		if value == "" {
+			return "", fmt.Errorf("AWS secret %s has no %s field", s.name, s.field)
+		}
+	}
+
+	apiKey := strings.TrimSpace(value)
+	if apiKey == "" {
+		return "", fmt.Errorf("AWS secret %s is empty", s.name)
+	}
+	return apiKey, nil
+}
+
+// APIKey implements Source
+func (s *AWSSSM) APIKey(ctx context.Context) (string, error) {
+	out, err := s.client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(s.name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot read AWS SSM parameter %s: %w", s.name, err)
+	}
+
+	apiKey := strings.TrimSpace(aws.StringValue(out.Parameter.Value))
+	if apiKey == "" {
+		return "", fmt.Errorf("AWS SSM parameter %s is empty", s.name)
+	}
+	return apiKey, nil
+}
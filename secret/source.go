@@ -0,0 +1,12 @@
+// Package secret fetches the Uptime Robot API key from secret stores, so that
+// it does not have to be given on the command line, in the environment or in
+// a file.
+package secret
+
+import "context"
+
+// Source is a secret store holding the API key
+type Source interface {
+	// APIKey fetches the current API key
+	APIKey(ctx context.Context) (string, error)
+}
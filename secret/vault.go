@@ -0,0 +1,234 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vault authentication methods
+const (
+	VaultAuthToken      = "token"
+	VaultAuthKubernetes = "kubernetes"
+	VaultAuthAppRole    = "approle"
+)
+
+// DefaultKubernetesJWTFile is where Kubernetes mounts the service account
+// token of the pods
+const DefaultKubernetesJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// vaultTimeout bounds each request made to Vault
+const vaultTimeout = 10 * time.Second
+
+// VaultOptions configures the access to the API key stored in Vault
+type VaultOptions struct {
+	// Address is the base URL of the Vault server
+	Address string
+	// AuthMethod is the authentication method, one of VaultAuthToken,
+	// VaultAuthKubernetes and VaultAuthAppRole
+	AuthMethod string
+	// AuthMount is the path the authentication method is mounted on,
+	// AuthMethod when empty
+	AuthMount string
+	// Token is the Vault token of the token authentication method
+	Token string
+	// Role is the role of the kubernetes authentication method
+	Role string
+	// JWTFile holds the service account token of the kubernetes
+	// authentication method, DefaultKubernetesJWTFile when empty
+	JWTFile string
+	// RoleID and SecretIDFile are the role ID and the path of the file
+	// holding the secret ID of the approle authentication method
+	RoleID       string
+	SecretIDFile string
+	// SecretPath is the path of the secret holding the API key, such as
+	// secret/data/uptimerobot for a KV version 2 secrets engine mounted on
+	// secret
+	SecretPath string
+	// SecretField is the field of the secret holding the API key
+	SecretField string
+}
+
+// Vault reads the API key from a HashiCorp Vault KV secret
+type Vault struct {
+	opts       VaultOptions
+	httpClient *http.Client
+
+	// mu protects the token obtained by logging in, which is renewed by
+	// logging in again once expired
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewVault validates the options and returns a Vault secret source
+func NewVault(opts VaultOptions) (*Vault, error) {
+	if opts.Address == "" {
+		return nil, errors.New("missing Vault address")
+	}
+	if opts.SecretPath == "" {
+		return nil, errors.New("missing Vault secret path")
+	}
+	if opts.SecretField == "" {
+		return nil, errors.New("missing Vault secret field")
+	}
+	switch opts.AuthMethod {
+	case VaultAuthToken:
+		if opts.Token == "" {
+			return nil, errors.New("missing Vault token")
+		}
+	case VaultAuthKubernetes:
+		if opts.Role == "" {
+			return nil, errors.New("missing Vault role")
+		}
+		if opts.JWTFile == "" {
+			opts.JWTFile = DefaultKubernetesJWTFile
+		}
+	case VaultAuthAppRole:
+		if opts.RoleID == "" || opts.SecretIDFile == "" {
+			return nil, errors.New("missing Vault role ID or secret ID file")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Vault auth method %q, expected token, kubernetes or approle", opts.AuthMethod)
+	}
+	if opts.AuthMount == "" {
+		opts.AuthMount = opts.AuthMethod
+	}
+	opts.Address = strings.TrimSuffix(opts.Address, "/")
+
+	return &Vault{
+		opts:       opts,
+		httpClient: &http.Client{Timeout: vaultTimeout},
+	}, nil
+}
+
+// APIKey implements Source, logging in first if needed
+func (v *Vault) APIKey(ctx context.Context) (string, error) {
+	token, err := v.login(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, v.opts.SecretPath, token, nil, &secret); err != nil {
+		return "", fmt.Errorf("cannot read Vault secret %s: %w", v.opts.SecretPath, err)
+	}
+
+	// KV version 2 secrets are nested in a second data object
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data[v.opts.SecretField]; !ok {
+			data = nested
+		}
+	}
+	apiKey, ok := data[v.opts.SecretField].(string)
+	if !ok || apiKey == "" {
+		return "", fmt.Errorf("Vault secret %s has no %s field", v.opts.SecretPath, v.opts.SecretField)
+	}
+	return apiKey, nil
+}
+
+// login returns a Vault token, logging in with the authentication method if
+// there is no valid token yet
+func (v *Vault) login(ctx context.Context) (string, error) {
+	if v.opts.AuthMethod == VaultAuthToken {
+		return v.opts.Token, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.token != "" && time.Now().Before(v.tokenExpiry) {
+		return v.token, nil
+	}
+
+	var payload map[string]string
+	switch v.opts.AuthMethod {
+	case VaultAuthKubernetes:
+		jwt, err := readTrimmed(v.opts.JWTFile)
+		if err != nil {
+			return "", err
+		}
+		payload = map[string]string{"role": v.opts.Role, "jwt": jwt}
+	case VaultAuthAppRole:
+		secretID, err := readTrimmed(v.opts.SecretIDFile)
+		if err != nil {
+			return "", err
+		}
+		payload = map[string]string{"role_id": v.opts.RoleID, "secret_id": secretID}
+	}
+
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	path := "auth/" + v.opts.AuthMount + "/login"
+	if err := v.do(ctx, http.MethodPost, path, "", payload, &resp); err != nil {
+		return "", fmt.Errorf("cannot log in to Vault: %w", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", errors.New("cannot log in to Vault: no token returned")
+	}
+
+	// log in again a bit before the token expires
+	v.token = resp.Auth.ClientToken
+	v.tokenExpiry = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second * 4 / 5)
+	return v.token, nil
+}
+
+// do makes a request to the Vault HTTP API and decodes its JSON answer in out
+func (v *Vault) do(ctx context.Context, method, path, token string, payload, out interface{}) error {
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.opts.Address+"/v1/"+strings.TrimPrefix(path, "/"), &body)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(content, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("HTTP status %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, ", "))
+		}
+		return fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(content, out)
+}
+
+// readTrimmed returns the content of a file without surrounding whitespace
+func readTrimmed(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/config"
+)
+
+// The settings of the exporter are resolved in a single place, with the
+// following precedence:
+//
+//  1. the command line flags
+//  2. the env variables named after the flags, see flagEnv
+//  3. the configuration file, for the settings it holds
+//  4. the default values of the flags
+//
+// The flags and their env variables are parsed by the flag package, so a
+// value of the wrong type is rejected at startup with the name of the flag or
+// of the env variable. The configuration file is parsed and validated by the
+// config package.
+
+// settingSource tells where the value of a setting comes from
+type settingSource int
+
+// Sources of the settings, by increasing precedence
+const (
+	sourceDefault settingSource = iota
+	sourceConfig
+	sourceEnv
+	sourceFlag
+)
+
+func (s settingSource) String() string {
+	switch s {
+	case sourceConfig:
+		return "configuration file"
+	case sourceEnv:
+		return "env variable"
+	case sourceFlag:
+		return "command line"
+	default:
+		return "default"
+	}
+}
+
+// envPrefix is the prefix of the env variables setting the flags
+const envPrefix = "UPTIMEROBOT_EXPORTER_"
+
+// legacyFlagEnvs are the env variables setting flags that predate the
+// envPrefix variables, which take precedence over them
+var legacyFlagEnvs = map[string]string{
+	"api-key": "UPTIMEROBOT_API_KEY",
+}
+
+// flagEnv returns the name of the env variable setting the flag named name,
+// for instance UPTIMEROBOT_EXPORTER_LOG_LEVEL for -log-level
+func flagEnv(name string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// lookupFlagEnv returns the value of the env variable setting the flag named
+// name and the name of that variable, if it is set
+func lookupFlagEnv(name string) (string, string, bool) {
+	for _, env := range []string{flagEnv(name), legacyFlagEnvs[name]} {
+		if env == "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(env); ok {
+			return value, env, true
+		}
+	}
+	return "", "", false
+}
+
+// setFlagsFromEnv sets the flags not given on the command line from their
+// env variable, if any. It returns the source of the flags that are set,
+// which then take precedence over the configuration file.
+func setFlagsFromEnv(fs *flag.FlagSet) (map[string]settingSource, error) {
+	sources := make(map[string]settingSource)
+	fs.Visit(func(f *flag.Flag) {
+		sources[f.Name] = sourceFlag
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if sources[f.Name] == sourceFlag || err != nil {
+			return
+		}
+		value, env, ok := lookupFlagEnv(f.Name)
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for env variable %s: %w", value, env, setErr)
+			return
+		}
+		sources[f.Name] = sourceEnv
+	})
+	return sources, err
+}
+
+// resolver resolves the settings that can be given both by a flag and by the
+// configuration file
+type resolver struct {
+	a   *app
+	cfg *config.Config
+}
+
+// resolver returns the resolver of the settings given by cfg
+func (a *app) resolver(cfg *config.Config) resolver {
+	return resolver{a: a, cfg: cfg}
+}
+
+// source returns where the setting of the flag named name comes from, given
+// whether the configuration file sets it
+func (r resolver) source(name string, inConfig bool) settingSource {
+	if source, ok := r.a.flagSources[name]; ok {
+		return source
+	}
+	if inConfig {
+		return sourceConfig
+	}
+	return sourceDefault
+}
+
+// log logs where the setting of the flag named name comes from
+func (r resolver) log(name string, source settingSource) {
+	r.a.logger.Debug().Str("source", source.String()).Msgf("setting -%s", name)
+}
+
+// string resolves a string setting, set in the configuration file when not
+// empty
+func (r resolver) string(name, flagValue, cfgValue string) string {
+	source := r.source(name, cfgValue != "")
+	r.log(name, source)
+	if source == sourceConfig {
+		return cfgValue
+	}
+	return flagValue
+}
+
+// duration resolves a duration setting, set in the configuration file when
+// not zero
+func (r resolver) duration(name string, flagValue, cfgValue time.Duration) time.Duration {
+	source := r.source(name, cfgValue != 0)
+	r.log(name, source)
+	if source == sourceConfig {
+		return cfgValue
+	}
+	return flagValue
+}
+
+// fromConfig resolves a setting of another kind, set in the configuration
+// file when inConfig, and reports whether its value comes from the file
+func (r resolver) fromConfig(name string, inConfig bool) bool {
+	source := r.source(name, inConfig)
+	r.log(name, source)
+	return source == sourceConfig
+}
+
+// list resolves a list setting, given as a comma-separated flag, set in the
+// configuration file when not empty. It returns nil when the list is empty.
+func (r resolver) list(name, flagValue string, cfgValue []string) []string {
+	source := r.source(name, len(cfgValue) > 0)
+	r.log(name, source)
+	if source == sourceConfig {
+		return cfgValue
+	}
+	if flagValue == "" {
+		return nil
+	}
+	return strings.Split(flagValue, ",")
+}
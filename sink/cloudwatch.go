@@ -0,0 +1,141 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Limits of each PutMetricData request
+const (
+	maxCloudWatchData       = 20
+	maxCloudWatchDimensions = 10
+)
+
+// CloudWatchOptions configures the writes to CloudWatch
+type CloudWatchOptions struct {
+	// Namespace is the CloudWatch namespace of the metrics
+	Namespace string
+	// Dimensions are the names of the labels written as dimensions, all of
+	// them when empty
+	Dimensions []string
+	// Region overrides the AWS region found in the environment
+	Region string
+}
+
+// CloudWatch publishes the samples to AWS CloudWatch with PutMetricData, the
+// labels becoming dimensions. The credentials and the region are found the
+// usual AWS way, so that the IAM role of an ECS task or of an EKS service
+// account is used.
+type CloudWatch struct {
+	opts   CloudWatchOptions
+	client *cloudwatch.CloudWatch
+}
+
+// NewCloudWatch validates the options and returns a CloudWatch sink
+func NewCloudWatch(opts CloudWatchOptions) (*CloudWatch, error) {
+	if strings.HasPrefix(opts.Namespace, "AWS/") {
+		return nil, errors.New("the AWS/ namespaces are reserved for the AWS services")
+	}
+	if len(opts.Dimensions) > maxCloudWatchDimensions {
+		return nil, fmt.Errorf("at most %d dimensions are allowed", maxCloudWatchDimensions)
+	}
+	cfg := aws.NewConfig()
+	if opts.Region != "" {
+		cfg = cfg.WithRegion(opts.Region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot configure AWS session: %w", err)
+	}
+	return &CloudWatch{opts: opts, client: cloudwatch.New(sess)}, nil
+}
+
+// Name implements Sink
+func (s *CloudWatch) Name() string {
+	return "CloudWatch"
+}
+
+// Write implements Sink
+func (s *CloudWatch) Write(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	var data []*cloudwatch.MetricDatum
+	var tooManyDimensions int
+	for _, sample := range Samples(families, now) {
+		// CloudWatch rejects the infinite values
+		if math.IsInf(sample.Value, 0) {
+			continue
+		}
+		dimensions := s.dimensions(sample)
+		if len(dimensions) > maxCloudWatchDimensions {
+			tooManyDimensions++
+			continue
+		}
+		data = append(data, &cloudwatch.MetricDatum{
+			MetricName: aws.String(sample.Name),
+			Dimensions: dimensions,
+			Value:      aws.Float64(sample.Value),
+			Timestamp:  aws.Time(sample.Time),
+			Unit:       aws.String(unit(sample.Name)),
+		})
+	}
+
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxCloudWatchData {
+			n = maxCloudWatchData
+		}
+		_, err := s.client.PutMetricDataWithContext(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(s.opts.Namespace),
+			MetricData: data[:n],
+		})
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+
+	if tooManyDimensions > 0 {
+		return fmt.Errorf("%d samples have more than %d labels and were left out, restrict the labels written as dimensions", tooManyDimensions, maxCloudWatchDimensions)
+	}
+	return nil
+}
+
+// dimensions returns the dimensions of sample, leaving out the empty labels
+// that CloudWatch rejects
+func (s *CloudWatch) dimensions(sample Sample) []*cloudwatch.Dimension {
+	names := s.opts.Dimensions
+	if len(names) == 0 {
+		names = sample.LabelNames()
+	}
+	var dimensions []*cloudwatch.Dimension
+	for _, name := range names {
+		if value := sample.Labels[name]; value != "" {
+			dimensions = append(dimensions, &cloudwatch.Dimension{Name: aws.String(name), Value: aws.String(value)})
+		}
+	}
+	return dimensions
+}
+
+// unit returns the CloudWatch unit of a metric from the suffix of its name
+func unit(name string) string {
+	name = strings.TrimSuffix(name, "_sum")
+	switch {
+	case strings.HasSuffix(name, "_seconds"):
+		return cloudwatch.StandardUnitSeconds
+	case strings.HasSuffix(name, "_bytes"):
+		return cloudwatch.StandardUnitBytes
+	default:
+		return cloudwatch.StandardUnitNone
+	}
+}
@@ -0,0 +1,123 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DatadogOptions configures the submissions to Datadog
+type DatadogOptions struct {
+	// URL is the base URL of the Datadog API of the site of the account,
+	// such as https://api.datadoghq.com or https://api.datadoghq.eu
+	URL string
+	// APIKey is the Datadog API key the metrics are submitted with
+	APIKey string
+	// Tags are added to the tags of all the series, as key:value
+	Tags []string
+}
+
+// Datadog submits the samples to the metrics API of Datadog as gauges, the
+// counters included, the labels becoming key:value tags
+type Datadog struct {
+	opts       DatadogOptions
+	seriesURL  string
+	httpClient *http.Client
+}
+
+// NewDatadog validates the options and returns a Datadog sink
+func NewDatadog(opts DatadogOptions) (*Datadog, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Datadog URL %q, it must be an http or https URL", u.Redacted())
+	}
+	if opts.APIKey == "" {
+		return nil, errors.New("missing Datadog API key")
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v1/series"
+	return &Datadog{
+		opts:       opts,
+		seriesURL:  u.String(),
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Name implements Sink
+func (s *Datadog) Name() string {
+	return "Datadog"
+}
+
+// datadogSeries is a series of the metrics API
+type datadogSeries struct {
+	Metric string       `json:"metric"`
+	Points [][2]float64 `json:"points"`
+	Type   string       `json:"type"`
+	Tags   []string     `json:"tags,omitempty"`
+}
+
+// Write implements Sink
+func (s *Datadog) Write(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	var series []datadogSeries
+	for _, sample := range Samples(families, now) {
+		// JSON cannot hold infinite values
+		if math.IsInf(sample.Value, 0) {
+			continue
+		}
+		tags := append([]string(nil), s.opts.Tags...)
+		for _, name := range sample.LabelNames() {
+			if value := sample.Labels[name]; value != "" {
+				tags = append(tags, name+":"+value)
+			}
+		}
+		series = append(series, datadogSeries{
+			Metric: sample.Name,
+			Points: [][2]float64{{float64(sample.Time.Unix()), sample.Value}},
+			Type:   "gauge",
+			Tags:   tags,
+		})
+	}
+
+	// the payloads are limited to 3.2 MB, which the series of a large account
+	// could exceed uncompressed
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if err := json.NewEncoder(zw).Encode(map[string][]datadogSeries{"series": series}); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.seriesURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("DD-API-KEY", s.opts.APIKey)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Datadog answered %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
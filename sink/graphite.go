@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// GraphiteOptions configures the writes to Graphite
+type GraphiteOptions struct {
+	// Address is the host:port of the Carbon plaintext receiver
+	Address string
+	// Prefix is prepended to the metric paths, followed by a dot, when not
+	// empty
+	Prefix string
+	// Tags writes the labels as Graphite 1.1 tags instead of path nodes
+	Tags bool
+}
+
+// Graphite writes the samples to Carbon in the plaintext protocol. Without
+// tags, each label becomes two nodes of the metric path, its name and its
+// value, after the metric name: uptimerobot_monitors_status.monitor_id.1234.
+type Graphite struct {
+	opts GraphiteOptions
+}
+
+// NewGraphite validates the options and returns a Graphite sink
+func NewGraphite(opts GraphiteOptions) (*Graphite, error) {
+	if _, _, err := net.SplitHostPort(opts.Address); err != nil {
+		return nil, err
+	}
+	opts.Prefix = strings.Trim(opts.Prefix, ".")
+	return &Graphite{opts: opts}, nil
+}
+
+// Name implements Sink
+func (s *Graphite) Name() string {
+	return "Graphite"
+}
+
+// Write implements Sink
+func (s *Graphite) Write(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	samples := Samples(families, now)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.opts.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(requestTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(conn)
+	for _, sample := range samples {
+		if math.IsInf(sample.Value, 0) {
+			continue
+		}
+		w.WriteString(s.path(sample))
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatFloat(sample.Value, 'g', -1, 64))
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatInt(sample.Time.Unix(), 10))
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
+
+// path returns the metric path of the sample
+func (s *Graphite) path(sample Sample) string {
+	var b strings.Builder
+	if s.opts.Prefix != "" {
+		b.WriteString(s.opts.Prefix)
+		b.WriteByte('.')
+	}
+	b.WriteString(sample.Name)
+	for _, name := range sample.LabelNames() {
+		value := sample.Labels[name]
+		if s.opts.Tags {
+			// tag values cannot be empty nor hold ; or ~ at their start
+			if value == "" {
+				continue
+			}
+			b.WriteByte(';')
+			b.WriteString(name)
+			b.WriteByte('=')
+			b.WriteString(strings.TrimLeft(tagValueEscaper.Replace(value), "~"))
+			continue
+		}
+		b.WriteByte('.')
+		b.WriteString(name)
+		b.WriteByte('.')
+		b.WriteString(pathNode(value))
+	}
+	return b.String()
+}
+
+// tagValueEscaper removes the characters that cannot appear in Graphite tag
+// values
+var tagValueEscaper = strings.NewReplacer(";", "_", " ", "_", "\n", "_")
+
+// pathNode returns value as a single node of a metric path, replacing the
+// characters other than letters, digits, _ and - with _
+func pathNode(value string) string {
+	if value == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, value)
+}
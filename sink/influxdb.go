@@ -0,0 +1,149 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// requestTimeout bounds each request made to the sinks
+const requestTimeout = 30 * time.Second
+
+// InfluxDBOptions configures the writes to InfluxDB. The InfluxDB 2 API is
+// used when Bucket is set, and the InfluxDB 1 API otherwise.
+type InfluxDBOptions struct {
+	// URL is the base URL of the InfluxDB server. With the InfluxDB 1 API,
+	// its credentials, if any, are sent with basic authentication.
+	URL string
+	// Database and RetentionPolicy are where the points are written with the
+	// InfluxDB 1 API, the default retention policy of the database being
+	// used when RetentionPolicy is empty
+	Database        string
+	RetentionPolicy string
+	// Org, Bucket and Token are where and with which token the points are
+	// written with the InfluxDB 2 API
+	Org    string
+	Bucket string
+	Token  string
+}
+
+// InfluxDB writes the samples to InfluxDB in the line protocol, a point per
+// sample, measured by the metric name, tagged with the labels and holding the
+// value in the value field
+type InfluxDB struct {
+	writeURL   string
+	token      string
+	httpClient *http.Client
+}
+
+// NewInfluxDB validates the options and returns an InfluxDB sink
+func NewInfluxDB(opts InfluxDBOptions) (*InfluxDB, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid InfluxDB URL %q, it must be an http or https URL", u.Redacted())
+	}
+
+	query := url.Values{"precision": {"s"}}
+	if opts.Bucket != "" {
+		if opts.Org == "" {
+			return nil, errors.New("missing InfluxDB organization")
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/write"
+		query.Set("org", opts.Org)
+		query.Set("bucket", opts.Bucket)
+	} else {
+		if opts.Database == "" {
+			return nil, errors.New("missing InfluxDB database or bucket")
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/write"
+		query.Set("db", opts.Database)
+		if opts.RetentionPolicy != "" {
+			query.Set("rp", opts.RetentionPolicy)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return &InfluxDB{
+		writeURL:   u.String(),
+		token:      opts.Token,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Name implements Sink
+func (s *InfluxDB) Name() string {
+	return "InfluxDB"
+}
+
+// Write implements Sink
+func (s *InfluxDB) Write(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	samples := Samples(families, now)
+	var body bytes.Buffer
+	for _, sample := range samples {
+		writeLine(&body, sample)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.writeURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("InfluxDB answered %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+var (
+	// measurementEscaper and tagEscaper escape the measurements and the tag
+	// keys and values of the line protocol, which cannot hold newlines
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`, "\n", " ")
+)
+
+// writeLine writes the sample as a line protocol point. Empty labels are left
+// out, as InfluxDB rejects empty tag values, and so are infinite values.
+func writeLine(w *bytes.Buffer, sample Sample) {
+	if math.IsInf(sample.Value, 0) {
+		return
+	}
+	w.WriteString(measurementEscaper.Replace(sample.Name))
+	for _, name := range sample.LabelNames() {
+		value := sample.Labels[name]
+		if value == "" {
+			continue
+		}
+		w.WriteByte(',')
+		w.WriteString(tagEscaper.Replace(name))
+		w.WriteByte('=')
+		w.WriteString(tagEscaper.Replace(value))
+	}
+	w.WriteString(" value=")
+	w.WriteString(strconv.FormatFloat(sample.Value, 'g', -1, 64))
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatInt(sample.Time.Unix(), 10))
+	w.WriteByte('\n')
+}
@@ -0,0 +1,300 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// OTLPOptions configures the export to an OpenTelemetry collector
+type OTLPOptions struct {
+	// Endpoint is the base URL of the OTLP/HTTP receiver, the metrics being
+	// posted to its /v1/metrics path
+	Endpoint string
+	// Headers are sent with each request, for instance to authenticate
+	Headers map[string]string
+	// ResourceAttributes describe the exporter as the source of the metrics
+	ResourceAttributes map[string]string
+	// ScopeName and ScopeVersion name the instrumentation scope of the
+	// metrics
+	ScopeName    string
+	ScopeVersion string
+}
+
+// OTLP exports the metrics in OTLP/HTTP with the JSON encoding. The gauges
+// become gauges, the counters cumulative monotonic sums, and the histograms
+// and summaries keep their type, with the labels as attributes.
+type OTLP struct {
+	opts       OTLPOptions
+	url        string
+	start      time.Time
+	httpClient *http.Client
+}
+
+// NewOTLP validates the options and returns an OTLP sink
+func NewOTLP(opts OTLPOptions) (*OTLP, error) {
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q, it must be an http or https URL", u.Redacted())
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/metrics"
+	return &OTLP{
+		opts: opts,
+		url:  u.String(),
+		// the cumulative metrics are counted since the exporter started
+		start:      time.Now(),
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Name implements Sink
+func (s *OTLP) Name() string {
+	return "OTLP"
+}
+
+// Write implements Sink
+func (s *OTLP) Write(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	body, err := json.Marshal(s.request(families, now))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.opts.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("OTLP receiver answered %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// The OTLP messages, in their JSON encoding. The 64-bit integers are encoded
+// as strings, as protobuf does in JSON.
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpMetric struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Gauge       *otlpGauge     `json:"gauge,omitempty"`
+		Sum         *otlpSum       `json:"sum,omitempty"`
+		Histogram   *otlpHistogram `json:"histogram,omitempty"`
+		Summary     *otlpSummary   `json:"summary,omitempty"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpNumberPoint `json:"dataPoints"`
+	}
+	otlpSum struct {
+		DataPoints             []otlpNumberPoint `json:"dataPoints"`
+		AggregationTemporality int               `json:"aggregationTemporality"`
+		IsMonotonic            bool              `json:"isMonotonic"`
+	}
+	otlpNumberPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		AsDouble          float64         `json:"asDouble"`
+	}
+	otlpHistogram struct {
+		DataPoints             []otlpHistogramPoint `json:"dataPoints"`
+		AggregationTemporality int                  `json:"aggregationTemporality"`
+	}
+	otlpHistogramPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		Count             string          `json:"count"`
+		Sum               float64         `json:"sum"`
+		BucketCounts      []string        `json:"bucketCounts"`
+		ExplicitBounds    []float64       `json:"explicitBounds"`
+	}
+	otlpSummary struct {
+		DataPoints []otlpSummaryPoint `json:"dataPoints"`
+	}
+	otlpSummaryPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		Count             string          `json:"count"`
+		Sum               float64         `json:"sum"`
+		QuantileValues    []otlpQuantile  `json:"quantileValues"`
+	}
+	otlpQuantile struct {
+		Quantile float64 `json:"quantile"`
+		Value    float64 `json:"value"`
+	}
+)
+
+// otlpCumulative is the cumulative aggregation temporality
+const otlpCumulative = 2
+
+// request returns the export request of the gathered metrics
+func (s *OTLP) request(families []*dto.MetricFamily, now time.Time) otlpRequest {
+	start := unixNano(s.start)
+	metrics := make([]otlpMetric, 0, len(families))
+	for _, mf := range families {
+		metric := otlpMetric{Name: mf.GetName(), Description: mf.GetHelp()}
+		for _, m := range mf.GetMetric() {
+			attrs := labelAttributes(m.GetLabel())
+			t := now
+			if m.TimestampMs != nil {
+				t = time.Unix(0, m.GetTimestampMs()*int64(time.Millisecond))
+			}
+			ts := unixNano(t)
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				if metric.Sum == nil {
+					metric.Sum = &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+				}
+				metric.Sum.DataPoints = append(metric.Sum.DataPoints, otlpNumberPoint{
+					Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: ts, AsDouble: m.GetCounter().GetValue(),
+				})
+			case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+				value := m.GetGauge().GetValue()
+				if mf.GetType() == dto.MetricType_UNTYPED {
+					value = m.GetUntyped().GetValue()
+				}
+				// NaN cannot be encoded in JSON
+				if math.IsNaN(value) || math.IsInf(value, 0) {
+					continue
+				}
+				if metric.Gauge == nil {
+					metric.Gauge = &otlpGauge{}
+				}
+				metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, otlpNumberPoint{
+					Attributes: attrs, TimeUnixNano: ts, AsDouble: value,
+				})
+			case dto.MetricType_HISTOGRAM:
+				if metric.Histogram == nil {
+					metric.Histogram = &otlpHistogram{AggregationTemporality: otlpCumulative}
+				}
+				metric.Histogram.DataPoints = append(metric.Histogram.DataPoints, histogramPoint(m.GetHistogram(), attrs, start, ts))
+			case dto.MetricType_SUMMARY:
+				if metric.Summary == nil {
+					metric.Summary = &otlpSummary{}
+				}
+				summary := m.GetSummary()
+				point := otlpSummaryPoint{
+					Attributes:        attrs,
+					StartTimeUnixNano: start,
+					TimeUnixNano:      ts,
+					Count:             strconv.FormatUint(summary.GetSampleCount(), 10),
+					Sum:               summary.GetSampleSum(),
+				}
+				for _, q := range summary.GetQuantile() {
+					if !math.IsNaN(q.GetValue()) {
+						point.QuantileValues = append(point.QuantileValues, otlpQuantile{Quantile: q.GetQuantile(), Value: q.GetValue()})
+					}
+				}
+				metric.Summary.DataPoints = append(metric.Summary.DataPoints, point)
+			}
+		}
+		if metric.Gauge != nil || metric.Sum != nil || metric.Histogram != nil || metric.Summary != nil {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: attributes(s.opts.ResourceAttributes)},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: s.opts.ScopeName, Version: s.opts.ScopeVersion},
+			Metrics: metrics,
+		}},
+	}}}
+}
+
+// histogramPoint returns the OTLP data point of a Prometheus histogram, whose
+// buckets are cumulative and may lack the +Inf one
+func histogramPoint(h *dto.Histogram, attrs []otlpAttribute, start, ts string) otlpHistogramPoint {
+	point := otlpHistogramPoint{
+		Attributes:        attrs,
+		StartTimeUnixNano: start,
+		TimeUnixNano:      ts,
+		Count:             strconv.FormatUint(h.GetSampleCount(), 10),
+		Sum:               h.GetSampleSum(),
+	}
+	var previous uint64
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			break
+		}
+		point.ExplicitBounds = append(point.ExplicitBounds, b.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(b.GetCumulativeCount()-previous, 10))
+		previous = b.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(h.GetSampleCount()-previous, 10))
+	return point
+}
+
+// labelAttributes returns the labels as OTLP attributes
+func labelAttributes(labels []*dto.LabelPair) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(labels))
+	for _, l := range labels {
+		attrs = append(attrs, otlpAttribute{Key: l.GetName(), Value: otlpValue{StringValue: l.GetValue()}})
+	}
+	return attrs
+}
+
+// attributes returns OTLP attributes sorted by key
+func attributes(m map[string]string) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(m))
+	for key, value := range m {
+		attrs = append(attrs, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
+
+// unixNano returns t as a string of nanoseconds since the Unix epoch
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,112 @@
+// Package sink writes the metrics of the exporter to time series databases
+// other than Prometheus, for the teams whose dashboards and alerts live
+// elsewhere.
+package sink
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Sink is a time series database the samples are written to
+type Sink interface {
+	// Name names the sink in the logs
+	Name() string
+	// Write writes the gathered metrics, taken at now
+	Write(ctx context.Context, families []*dto.MetricFamily, now time.Time) error
+}
+
+// Sample is a value of a metric
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+	Time   time.Time
+}
+
+// LabelNames returns the names of the labels of the sample, sorted
+func (s Sample) LabelNames() []string {
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Samples flattens gathered metric families into samples, as the Prometheus
+// text format does: the histograms and summaries give a sample per bucket or
+// quantile along with their _sum and _count. The samples without timestamp
+// are given now. NaN values are left out, as most databases reject them.
+func Samples(families []*dto.MetricFamily, now time.Time) []Sample {
+	var samples []Sample
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string, len(m.GetLabel())+1)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			t := now
+			if m.TimestampMs != nil {
+				t = time.Unix(0, m.GetTimestampMs()*int64(time.Millisecond))
+			}
+			add := func(name string, value float64, extra ...string) {
+				if math.IsNaN(value) {
+					return
+				}
+				l := labels
+				if len(extra) > 0 {
+					l = make(map[string]string, len(labels)+1)
+					for k, v := range labels {
+						l[k] = v
+					}
+					l[extra[0]] = extra[1]
+				}
+				samples = append(samples, Sample{Name: name, Labels: l, Value: value, Time: t})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				inf := false
+				for _, b := range h.GetBucket() {
+					inf = math.IsInf(b.GetUpperBound(), 1)
+					add(name+"_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound()))
+				}
+				if !inf {
+					add(name+"_bucket", float64(h.GetSampleCount()), "le", "+Inf")
+				}
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return samples
+}
+
+// formatFloat formats a bucket bound or a quantile as Prometheus does
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
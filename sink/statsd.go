@@ -0,0 +1,128 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxStatsDPacket is the maximum size of the UDP packets sent to StatsD,
+// small enough to never be fragmented
+const maxStatsDPacket = 1432
+
+// StatsDOptions configures the writes to StatsD
+type StatsDOptions struct {
+	// Address is the host:port StatsD listens on in UDP
+	Address string
+	// Prefix is prepended to the metric names, followed by a dot, when not
+	// empty
+	Prefix string
+	// Tags writes the labels as DogStatsD tags instead of nodes of the
+	// metric names
+	Tags bool
+}
+
+// StatsD sends the samples to StatsD as gauges, the counters included since
+// their value is the total and not an increment. Without tags, each label
+// becomes two nodes of the metric name, as with Graphite.
+type StatsD struct {
+	opts StatsDOptions
+}
+
+// NewStatsD validates the options and returns a StatsD sink
+func NewStatsD(opts StatsDOptions) (*StatsD, error) {
+	if _, _, err := net.SplitHostPort(opts.Address); err != nil {
+		return nil, err
+	}
+	opts.Prefix = strings.Trim(opts.Prefix, ".")
+	return &StatsD{opts: opts}, nil
+}
+
+// Name implements Sink
+func (s *StatsD) Name() string {
+	return "StatsD"
+}
+
+// Write implements Sink
+func (s *StatsD) Write(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	samples := Samples(families, now)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", s.opts.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var packet bytes.Buffer
+	for _, sample := range samples {
+		if math.IsInf(sample.Value, 0) {
+			continue
+		}
+		line := s.line(sample)
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxStatsDPacket {
+			if _, err := conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		if _, err := conn.Write(packet.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// line returns the StatsD gauge of the sample. A negative value is sent after
+// setting the gauge to 0, as a signed value is taken as a change of the gauge.
+func (s *StatsD) line(sample Sample) string {
+	var name strings.Builder
+	if s.opts.Prefix != "" {
+		name.WriteString(s.opts.Prefix)
+		name.WriteByte('.')
+	}
+	name.WriteString(sample.Name)
+	var tags strings.Builder
+	for _, label := range sample.LabelNames() {
+		value := sample.Labels[label]
+		if !s.opts.Tags {
+			name.WriteByte('.')
+			name.WriteString(label)
+			name.WriteByte('.')
+			name.WriteString(pathNode(value))
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		if tags.Len() == 0 {
+			tags.WriteString("|#")
+		} else {
+			tags.WriteByte(',')
+		}
+		tags.WriteString(label)
+		tags.WriteByte(':')
+		tags.WriteString(dogStatsDEscaper.Replace(value))
+	}
+
+	gauge := name.String() + ":" + strconv.FormatFloat(sample.Value, 'f', -1, 64) + "|g" + tags.String()
+	if sample.Value < 0 {
+		return name.String() + ":0|g" + tags.String() + "\n" + gauge
+	}
+	return gauge
+}
+
+// dogStatsDEscaper replaces the characters that cannot appear in DogStatsD
+// tag values
+var dogStatsDEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
@@ -0,0 +1,93 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// textfileName is the name of the file written for the node exporter
+const textfileName = "uptimerobot.prom"
+
+// Textfile writes the metrics in the Prometheus text format to a .prom file
+// read by the textfile collector of the node exporter. The file is replaced
+// atomically, so that the node exporter never reads it half-written.
+type Textfile struct {
+	directory string
+}
+
+// NewTextfile returns a Textfile sink writing to directory, which must exist
+func NewTextfile(directory string) (*Textfile, error) {
+	info, err := os.Stat(directory)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", directory)
+	}
+	return &Textfile{directory: directory}, nil
+}
+
+// Name implements Sink
+func (s *Textfile) Name() string {
+	return "textfile"
+}
+
+// Path returns the path of the written file
+func (s *Textfile) Path() string {
+	return filepath.Join(s.directory, textfileName)
+}
+
+// Write implements Sink
+func (s *Textfile) Write(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	// the temporary file does not end with .prom, which the node exporter
+	// would read
+	tmp, err := ioutil.TempFile(s.directory, "."+textfileName+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := expfmt.NewEncoder(tmp, expfmt.FmtText)
+	for _, mf := range families {
+		if err := enc.Encode(withoutTimestamps(mf)); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// the node exporter may run as another user
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path())
+}
+
+// withoutTimestamps returns mf without the timestamps of its metrics, which
+// the node exporter rejects
+func withoutTimestamps(mf *dto.MetricFamily) *dto.MetricFamily {
+	metrics := make([]*dto.Metric, 0, len(mf.GetMetric()))
+	for _, m := range mf.GetMetric() {
+		metrics = append(metrics, &dto.Metric{
+			Label:     m.Label,
+			Gauge:     m.Gauge,
+			Counter:   m.Counter,
+			Summary:   m.Summary,
+			Untyped:   m.Untyped,
+			Histogram: m.Histogram,
+		})
+	}
+	return &dto.MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type, Metric: metrics}
+}
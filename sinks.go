@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+	"github.com/eze-kiel/uptimerobot-exporter/sink"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/version"
+)
+
+// scheduledSink is a sink the metrics are written to every interval
+type scheduledSink struct {
+	sink.Sink
+	interval time.Duration
+}
+
+// newSinks returns the sinks enabled by the flags
+func (a *app) newSinks() ([]scheduledSink, error) {
+	var sinks []scheduledSink
+	if a.influxDB.URL != "" {
+		token, err := flagOrFile(a.influxDB.Token, a.influxTokenFile, "influxdb.token")
+		if err != nil {
+			return nil, err
+		}
+		opts := a.influxDB
+		opts.Token = token
+		s, err := sink.NewInfluxDB(opts)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, scheduledSink{s, a.influxInterval})
+	}
+	if a.graphite.Address != "" {
+		s, err := sink.NewGraphite(a.graphite)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -graphite.address: %w", err)
+		}
+		sinks = append(sinks, scheduledSink{s, a.graphiteInterval})
+	}
+	if a.statsd.Address != "" {
+		s, err := sink.NewStatsD(a.statsd)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -statsd.address: %w", err)
+		}
+		sinks = append(sinks, scheduledSink{s, a.statsdInterval})
+	}
+	if a.otlp.Endpoint != "" {
+		opts := a.otlp
+		opts.Headers = a.otlpHeaders
+		for _, value := range opts.Headers {
+			logger.AddSecret(value)
+		}
+		opts.ResourceAttributes = a.otlpResourceAttributes()
+		opts.ScopeName = "uptimerobot-exporter"
+		opts.ScopeVersion = version.Version
+		s, err := sink.NewOTLP(opts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -otlp.endpoint: %w", err)
+		}
+		sinks = append(sinks, scheduledSink{s, a.otlpInterval})
+	}
+	if a.cloudWatch.Namespace != "" {
+		opts := a.cloudWatch
+		if a.cloudWatchDims != "" {
+			for _, name := range strings.Split(a.cloudWatchDims, ",") {
+				opts.Dimensions = append(opts.Dimensions, strings.TrimSpace(name))
+			}
+		}
+		s, err := sink.NewCloudWatch(opts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CloudWatch options: %w", err)
+		}
+		sinks = append(sinks, scheduledSink{s, a.cloudWatchInterval})
+	}
+	if a.datadog.APIKey != "" || a.datadogKeyFile != "" {
+		apiKey, err := flagOrFile(a.datadog.APIKey, a.datadogKeyFile, "datadog.api-key")
+		if err != nil {
+			return nil, err
+		}
+		opts := a.datadog
+		opts.APIKey = apiKey
+		if a.datadogTags != "" {
+			for _, tag := range strings.Split(a.datadogTags, ",") {
+				opts.Tags = append(opts.Tags, strings.TrimSpace(tag))
+			}
+		}
+		s, err := sink.NewDatadog(opts)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, scheduledSink{s, a.datadogInterval})
+	}
+	if a.textfileDirectory != "" {
+		s, err := sink.NewTextfile(a.textfileDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -textfile.directory: %w", err)
+		}
+		sinks = append(sinks, scheduledSink{s, a.textfileInterval})
+	}
+
+	for _, s := range sinks {
+		if s.interval <= 0 {
+			return nil, fmt.Errorf("the %s interval must be positive", s.Name())
+		}
+	}
+	return sinks, nil
+}
+
+// otlpResourceAttributes returns the resource attributes of the OTLP exports:
+// the service name and version, when known, overridden by the OTEL_RESOURCE_ATTRIBUTES env
+// variable and then by -otlp.resource-attributes
+func (a *app) otlpResourceAttributes() map[string]string {
+	attrs := keyValues{"service.name": "uptimerobot-exporter"}
+	if version.Version != "" {
+		attrs["service.version"] = version.Version
+	}
+	if env := os.Getenv("OTEL_RESOURCE_ATTRIBUTES"); env != "" {
+		if err := attrs.Set(env); err != nil {
+			a.logger.Warn().Err(err).Msg("ignoring the invalid attributes of OTEL_RESOURCE_ATTRIBUTES")
+		}
+	}
+	for key, value := range a.otlpResource {
+		attrs[key] = value
+	}
+	return attrs
+}
+
+// flagOrFile returns the secret given with the flag named name or held by the
+// file given with the flag named name-file, which are mutually exclusive
+func flagOrFile(value, file, name string) (string, error) {
+	if file == "" {
+		logger.AddSecret(value)
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("-%s and -%s-file are mutually exclusive", name, name)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("cannot read -%s-file: %w", name, err)
+	}
+	secret := strings.TrimSpace(string(content))
+	logger.AddSecret(secret)
+	return secret, nil
+}
+
+// sharedGatherer gathers the metrics of a gatherer at most once every maxAge,
+// so that the sinks and the Pushgateway share the gatherings rather than each
+// running its own, which queries the API in on-demand mode. The gathered
+// families must not be modified.
+type sharedGatherer struct {
+	gatherer prometheus.Gatherer
+	maxAge   time.Duration
+
+	mu       sync.Mutex
+	at       time.Time
+	families []*dto.MetricFamily
+	err      error
+}
+
+// newSharedGatherer returns a gatherer sharing the gatherings of g between the
+// exports made every one of intervals. The gatherings are kept for half the
+// shortest interval, so that each tick of the exports made on the same
+// schedule, which do not fire at the exact same time, gathers afresh.
+func newSharedGatherer(g prometheus.Gatherer, intervals []time.Duration) *sharedGatherer {
+	shortest := intervals[0]
+	for _, interval := range intervals[1:] {
+		if interval < shortest {
+			shortest = interval
+		}
+	}
+	return &sharedGatherer{gatherer: g, maxAge: shortest / 2}
+}
+
+// Gather implements prometheus.Gatherer
+func (g *sharedGatherer) Gather() ([]*dto.MetricFamily, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.at.IsZero() || time.Since(g.at) >= g.maxAge {
+		g.at = time.Now()
+		g.families, g.err = g.gatherer.Gather()
+	}
+	return g.families, g.err
+}
+
+// writeEvery writes the metrics gathered by g to s right away and then every
+// interval, until ctx is done
+func (a *app) writeEvery(ctx context.Context, s scheduledSink, g prometheus.Gatherer) {
+	a.logger.Info().Msgf("writing the metrics to %s every %s", s.Name(), s.interval)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		families, err := g.Gather()
+		if err != nil {
+			a.logger.Warn().Err(err).Msgf("some metrics could not be gathered for %s", s.Name())
+		}
+		writeCtx, cancel := context.WithTimeout(ctx, s.interval)
+		if err := s.Write(writeCtx, a.selectSinkMetrics(families), time.Now()); err != nil {
+			a.logger.Error().Err(err).Msgf("cannot write the metrics to %s", s.Name())
+		} else {
+			a.logger.Debug().Msgf("metrics written to %s", s.Name())
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// selectSinkMetrics returns the families whose name matches -sinks.metrics
+func (a *app) selectSinkMetrics(families []*dto.MetricFamily) []*dto.MetricFamily {
+	if a.sinkMetrics == nil {
+		return families
+	}
+	// the families are shared with the other sinks
+	var selected []*dto.MetricFamily
+	for _, mf := range families {
+		if a.sinkMetrics.MatchString(mf.GetName()) {
+			selected = append(selected, mf)
+		}
+	}
+	return selected
+}
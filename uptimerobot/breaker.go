@@ -0,0 +1,115 @@
+package uptimerobot
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling the API while the circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open, API calls suspended")
+
+// BreakerPolicy configures the circuit breaker of a Client
+type BreakerPolicy struct {
+	// Threshold is the number of consecutive failed calls opening the
+	// breaker, 0 disables it
+	Threshold int
+	// Cooldown is how long the breaker stays open before a probe call is
+	// let through
+	Cooldown time.Duration
+}
+
+type breakerState int
+
+// The values of the breaker states are the ones of the exported gauge
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker stops calls to the API after too many consecutive failures. Once
+// the cooldown has elapsed a single probe call is allowed: the breaker closes
+// if it succeeds and opens again otherwise.
+type breaker struct {
+	policy BreakerPolicy
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+// allow reports whether a call can be made
+func (b *breaker) allow() bool {
+	if b.policy.Threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.policy.Cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// the probe call is in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of a call and returns the state
+// change it caused, if any
+func (b *breaker) record(failed bool) (from, to breakerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	from = b.state
+
+	if !failed {
+		b.failures = 0
+		b.state = breakerClosed
+		return from, b.state
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.policy.Threshold > 0 && b.failures >= b.policy.Threshold) {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+	return from, b.state
+}
+
+// abort is called when a call was cancelled before its outcome was known. A
+// cancelled probe call puts the breaker back in the open state, leaving the
+// next call free to probe.
+func (b *breaker) abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+// current returns the state of the breaker
+func (b *breaker) current() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
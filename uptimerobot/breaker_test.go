@@ -0,0 +1,139 @@
+package uptimerobot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestBreakerOpens(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   func(w http.ResponseWriter, r *http.Request, call int)
+		wantState breakerState
+		// requests is the number of requests received out of 4 calls
+		requests int
+	}{
+		{
+			name: "timeouts",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				hang(w, r)
+			},
+			wantState: breakerOpen,
+			requests:  2,
+		},
+		{
+			name: "server errors",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wantState: breakerOpen,
+			requests:  2,
+		},
+		{
+			name: "API errors",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				w.Write([]byte(`{"stat":"fail","error":{"type":"invalid_parameter"}}`))
+			},
+			wantState: breakerClosed,
+			requests:  4,
+		},
+		{
+			name: "failure then success",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				if call%2 == 1 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.Write([]byte(accountDetails))
+			},
+			wantState: breakerClosed,
+			requests:  4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := newTestServer(t, tt.handler)
+			c := New("u1-key", Options{
+				BaseURL: srv.URL,
+				Timeout: 50 * time.Millisecond,
+				Breaker: BreakerPolicy{Threshold: 2, Cooldown: time.Hour},
+				Logger:  zerolog.Nop(),
+			})
+			for i := 0; i < 4; i++ {
+				c.GetAccountDetails(context.Background())
+			}
+			if got := c.breaker.current(); got != tt.wantState {
+				t.Errorf("breaker %s, want %s", got, tt.wantState)
+			}
+			if got := int(atomic.LoadInt32(requests)); got != tt.requests {
+				t.Errorf("got %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name      string
+		probeOK   bool
+		wantState breakerState
+	}{
+		{name: "probe succeeds", probeOK: true, wantState: breakerClosed},
+		{name: "probe fails", probeOK: false, wantState: breakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var probed int32
+			srv, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, call int) {
+				if call == 2 {
+					atomic.StoreInt32(&probed, 1)
+					if tt.probeOK {
+						w.Write([]byte(accountDetails))
+						return
+					}
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+			c := New("u1-key", Options{
+				BaseURL: srv.URL,
+				Breaker: BreakerPolicy{Threshold: 1, Cooldown: 50 * time.Millisecond},
+				Logger:  zerolog.Nop(),
+			})
+
+			c.GetAccountDetails(context.Background())
+			if _, err := c.GetAccountDetails(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("call during the cooldown: got %v, want %v", err, ErrCircuitOpen)
+			}
+
+			time.Sleep(60 * time.Millisecond)
+			if !c.breaker.allow() {
+				t.Fatal("breaker does not allow a probe once the cooldown elapsed")
+			}
+			if got := c.breaker.current(); got != breakerHalfOpen {
+				t.Fatalf("breaker %s during the probe, want %s", got, breakerHalfOpen)
+			}
+			if c.breaker.allow() {
+				t.Error("breaker allows a second call during the probe")
+			}
+			// hand the probe over to an actual call
+			c.breaker.abort()
+
+			c.GetAccountDetails(context.Background())
+			if atomic.LoadInt32(&probed) == 0 {
+				t.Fatal("the probe call was not made")
+			}
+			if got := c.breaker.current(); got != tt.wantState {
+				t.Errorf("breaker %s after the probe, want %s", got, tt.wantState)
+			}
+			if got := atomic.LoadInt32(requests); got != 2 {
+				t.Errorf("got %d requests, want 2", got)
+			}
+		})
+	}
+}
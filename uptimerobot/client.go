@@ -0,0 +1,598 @@
+package uptimerobot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// DefaultBaseURL is the base URL of the Uptime Robot v2 API
+const DefaultBaseURL = "https://api.uptimerobot.com/v2"
+
+// DefaultMetricsPrefix is the prefix of the names of the exported metrics
+const DefaultMetricsPrefix = "uptimerobot_"
+
+// Client is a minimal Uptime Robot v2 API client
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	workers    int
+	retry      RetryPolicy
+	breaker    *breaker
+	logger     zerolog.Logger
+
+	// mu protects the API key, which can be changed at runtime
+	mu     sync.RWMutex
+	apiKey string
+
+	// monitorScoped is set by CheckAPIKey when the key is restricted to
+	// monitors and cannot read the account details
+	monitorScoped bool
+
+	// rateLimitedUntil is the time before which the API must not be called
+	// because of a 429 answer
+	rateLimitedUntil time.Time
+
+	// quota is the rate limit status reported by the latest response
+	quota *Quota
+
+	// called is set once a call was made, lastErrorType being the type of
+	// the error of the latest call
+	called        bool
+	lastErrorType string
+
+	// responses holds the latest response of each API method
+	responses map[string]Response
+
+	rateLimited     prometheus.Counter
+	breakerState    *prometheus.Desc
+	apiUp           *prometheus.Desc
+	quotaLimit      *prometheus.Desc
+	quotaRemaining  *prometheus.Desc
+	quotaReset      *prometheus.Desc
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+// Options holds the tunables of a Client
+type Options struct {
+	// BaseURL is the base URL of the API methods, DefaultBaseURL when empty
+	BaseURL string
+	// Timeout bounds each HTTP request made to the API, 0 means no timeout
+	Timeout time.Duration
+	// Proxy is the URL of the HTTP, HTTPS or SOCKS5 proxy used to reach the
+	// API. When nil, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+	// variables are honored.
+	Proxy *url.URL
+	// Workers is the number of getMonitors pages fetched concurrently
+	Workers int
+	// Retry is the retry policy of failed API calls
+	Retry RetryPolicy
+	// Breaker configures the circuit breaker suspending API calls during
+	// outages
+	Breaker BreakerPolicy
+	// Logger logs the client's internals
+	Logger zerolog.Logger
+	// MetricsPrefix is the prefix of the names of the client's metrics,
+	// DefaultMetricsPrefix when empty
+	MetricsPrefix string
+}
+
+// New creates a new Uptime Robot API client using the given API key
+func New(apiKey string, opts Options) *Client {
+	logger.AddSecret(apiKey)
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.Retry.MaxAttempts < 1 {
+		opts.Retry.MaxAttempts = 1
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != nil {
+		transport.Proxy = http.ProxyURL(opts.Proxy)
+	}
+
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultBaseURL
+	}
+	if opts.MetricsPrefix == "" {
+		opts.MetricsPrefix = DefaultMetricsPrefix
+	}
+
+	return &Client{
+		baseURL: strings.TrimSuffix(opts.BaseURL, "/"),
+		httpClient: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+		},
+		apiKey:  apiKey,
+		workers: opts.Workers,
+		retry:   opts.Retry,
+		breaker: &breaker{policy: opts.Breaker},
+		logger:  opts.Logger,
+
+		responses: make(map[string]Response),
+		rateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: opts.MetricsPrefix + "api_rate_limited_requests_total",
+			Help: "Number of API requests rejected or skipped because of the API rate limit",
+		}),
+		breakerState: prometheus.NewDesc(
+			opts.MetricsPrefix+"api_circuit_breaker_state",
+			"State of the API circuit breaker (0: closed, 1: half-open, 2: open)",
+			nil, nil,
+		),
+		apiUp: prometheus.NewDesc(
+			opts.MetricsPrefix+"api_up",
+			"Whether the latest API call succeeded (1) or not (0), error_type being the type of its error",
+			[]string{"error_type"}, nil,
+		),
+		quotaLimit: prometheus.NewDesc(
+			opts.MetricsPrefix+"api_rate_limit",
+			"Number of API requests allowed per rate limit period, as reported by the API",
+			nil, nil,
+		),
+		quotaRemaining: prometheus.NewDesc(
+			opts.MetricsPrefix+"api_rate_limit_remaining",
+			"Number of API requests left in the current rate limit period, as reported by the API",
+			nil, nil,
+		),
+		quotaReset: prometheus.NewDesc(
+			opts.MetricsPrefix+"api_rate_limit_reset_timestamp_seconds",
+			"End of the current rate limit period, as a Unix timestamp",
+			nil, nil,
+		),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: opts.MetricsPrefix + "api_requests_total",
+			Help: "Number of HTTP requests made to the API, by endpoint and status code (error when no response was received)",
+		}, []string{"endpoint", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    opts.MetricsPrefix + "api_request_duration_seconds",
+			Help:    "Duration of the HTTP requests made to the API, by endpoint",
+			Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"endpoint"}),
+	}
+}
+
+// Describe implements prometheus.Collector, exposing the client's own metrics
+func (c *Client) Describe(ch chan<- *prometheus.Desc) {
+	c.rateLimited.Describe(ch)
+	ch <- c.breakerState
+	ch <- c.apiUp
+	ch <- c.quotaLimit
+	ch <- c.quotaRemaining
+	ch <- c.quotaReset
+	c.requests.Describe(ch)
+	c.requestDuration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Client) Collect(ch chan<- prometheus.Metric) {
+	c.rateLimited.Collect(ch)
+	ch <- prometheus.MustNewConstMetric(c.breakerState, prometheus.GaugeValue, float64(c.breaker.current()))
+	c.requests.Collect(ch)
+	c.requestDuration.Collect(ch)
+
+	c.mu.RLock()
+	called, errorType := c.called, c.lastErrorType
+	c.mu.RUnlock()
+	if called {
+		ch <- prometheus.MustNewConstMetric(c.apiUp, prometheus.GaugeValue, boolToFloat(errorType == ""), errorType)
+	}
+
+	if quota := c.Quota(); quota != nil {
+		ch <- prometheus.MustNewConstMetric(c.quotaLimit, prometheus.GaugeValue, float64(quota.Limit))
+		ch <- prometheus.MustNewConstMetric(c.quotaRemaining, prometheus.GaugeValue, float64(quota.Remaining))
+		if !quota.Reset.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.quotaReset, prometheus.GaugeValue, float64(quota.Reset.Unix()))
+		}
+	}
+}
+
+// CheckAPIKey makes sure the API key is accepted by the API. Monitor-specific
+// keys cannot call getAccountDetails, so getMonitors is tried before giving up.
+func (c *Client) CheckAPIKey(ctx context.Context) error {
+	_, err := c.GetAccountDetails(ctx)
+	var apiErr *APIError
+	if err == nil || !errors.As(err, &apiErr) {
+		return err
+	}
+
+	if _, monitorsErr := c.getMonitorsPage(ctx, MonitorsQuery{}, 0); monitorsErr != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.monitorScoped = true
+	c.mu.Unlock()
+	return nil
+}
+
+// MonitorScoped reports whether the API key is restricted to monitors, as
+// detected by CheckAPIKey
+func (c *Client) MonitorScoped() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.monitorScoped
+}
+
+// APIKey returns the API key used by the client
+func (c *Client) APIKey() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.apiKey
+}
+
+// SetAPIKey replaces the API key used by the client. CheckAPIKey should be
+// called again afterwards to detect the scope of the new key.
+func (c *Client) SetAPIKey(apiKey string) {
+	logger.AddSecret(apiKey)
+	c.mu.Lock()
+	c.apiKey = apiKey
+	c.monitorScoped = false
+	c.mu.Unlock()
+}
+
+// UseAPIKeyOf replaces the API key used by the client with the one of
+// checked, along with the scope CheckAPIKey detected for it
+func (c *Client) UseAPIKeyOf(checked *Client) {
+	apiKey, monitorScoped := checked.APIKey(), checked.MonitorScoped()
+	c.mu.Lock()
+	c.apiKey = apiKey
+	c.monitorScoped = monitorScoped
+	c.mu.Unlock()
+}
+
+// GetAccountDetails calls the getAccountDetails endpoint
+func (c *Client) GetAccountDetails(ctx context.Context) (*AccountDetails, error) {
+	var account AccountDetails
+	if err := c.post(ctx, "getAccountDetails", url.Values{}, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// monitorsPageSize is the maximum number of monitors returned by a single
+// getMonitors call
+const monitorsPageSize = 50
+
+// MonitorsQuery holds the optional parameters of getMonitors
+type MonitorsQuery struct {
+	// IDs restricts the monitors to the given IDs when not empty
+	IDs []int
+	// Types restricts the monitors to the given types when not empty
+	Types []int
+	// Statuses restricts the monitors to the given statuses when not empty
+	Statuses []int
+	// Search restricts the monitors to the ones whose URL or friendly name
+	// contains it when not empty
+	Search string
+	// SSL requests the SSL certificate details of the monitors
+	SSL bool
+	// CustomUptimeRatios are the periods, in days, over which the uptime
+	// ratio of the monitors is computed
+	CustomUptimeRatios []int
+	// CustomDownDurations requests the downtime of the monitors over the
+	// CustomUptimeRatios periods
+	CustomDownDurations bool
+	// AllTimeUptime requests the uptime ratio and the up, down and paused
+	// durations of the monitors since their creation
+	AllTimeUptime bool
+	// Tags requests the tags of the monitors
+	Tags bool
+	// Logs requests the latest events of the monitors, restricted to
+	// LogTypes when not empty and to LogsLimit events per monitor when
+	// positive
+	Logs      bool
+	LogTypes  []int
+	LogsLimit int
+}
+
+// values returns the getMonitors parameters matching the query
+func (q MonitorsQuery) values() url.Values {
+	params := url.Values{
+		"response_times":       {"1"},
+		"response_times_limit": {"1"},
+	}
+	if len(q.IDs) > 0 {
+		params.Set("monitors", joinInts(q.IDs))
+	}
+	if len(q.Types) > 0 {
+		params.Set("types", joinInts(q.Types))
+	}
+	if len(q.Statuses) > 0 {
+		params.Set("statuses", joinInts(q.Statuses))
+	}
+	if q.Search != "" {
+		params.Set("search", q.Search)
+	}
+	if q.SSL {
+		params.Set("ssl", "1")
+	}
+	if len(q.CustomUptimeRatios) > 0 {
+		params.Set("custom_uptime_ratios", joinInts(q.CustomUptimeRatios))
+		if q.CustomDownDurations {
+			params.Set("custom_down_durations", "1")
+		}
+	}
+	if q.AllTimeUptime {
+		params.Set("all_time_uptime_ratio", "1")
+		params.Set("all_time_uptime_durations", "1")
+	}
+	if q.Tags {
+		params.Set("tags", "1")
+	}
+	if q.Logs {
+		params.Set("logs", "1")
+		if len(q.LogTypes) > 0 {
+			params.Set("logs_types", joinInts(q.LogTypes))
+		}
+		if q.LogsLimit > 0 {
+			params.Set("logs_limit", strconv.Itoa(q.LogsLimit))
+		}
+	}
+	return params
+}
+
+// joinInts joins numbers with dashes, as expected by the API for lists
+func joinInts(values []int) string {
+	fields := make([]string, len(values))
+	for i, v := range values {
+		fields[i] = strconv.Itoa(v)
+	}
+	return strings.Join(fields, "-")
+}
+
+// GetMonitors calls the getMonitors endpoint, including the latest response
+// time of each monitor. The first page tells how many monitors the account
+// has, the remaining pages are then fetched concurrently by the client
+// workers.
+func (c *Client) GetMonitors(ctx context.Context, query MonitorsQuery) ([]Monitor, error) {
+	first, err := c.getMonitorsPage(ctx, query, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := first.Pagination.Limit
+	if pageSize <= 0 {
+		pageSize = monitorsPageSize
+	}
+	total := first.Pagination.Total
+	if len(first.Monitors) == 0 || total <= len(first.Monitors) {
+		return first.Monitors, nil
+	}
+
+	pageCount := (total + pageSize - 1) / pageSize
+	pages := make([][]Monitor, pageCount)
+	pages[0] = first.Monitors
+
+	workers := c.workers
+	if workers > pageCount-1 {
+		workers = pageCount - 1
+	}
+
+	// the pages left are not fetched once one failed
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		jobs     = make(chan int)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				page, err := c.getMonitorsPage(ctx, query, idx*pageSize)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+				pages[idx] = page.Monitors
+			}
+		}()
+	}
+	for idx := 1; idx < pageCount && ctx.Err() == nil; idx++ {
+		select {
+		case jobs <- idx:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	monitors := make([]Monitor, 0, total)
+	for _, page := range pages {
+		monitors = append(monitors, page...)
+	}
+	return monitors, nil
+}
+
+func (c *Client) getMonitorsPage(ctx context.Context, query MonitorsQuery, offset int) (*MonitorsData, error) {
+	params := query.values()
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(monitorsPageSize))
+
+	var page MonitorsData
+	if err := c.post(ctx, "getMonitors", params, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetMWindows calls the getMWindows endpoint, returning all the maintenance
+// windows of the account
+func (c *Client) GetMWindows(ctx context.Context) ([]MWindow, error) {
+	var mwindows []MWindow
+	for {
+		params := url.Values{
+			"offset": {strconv.Itoa(len(mwindows))},
+			"limit":  {strconv.Itoa(monitorsPageSize)},
+		}
+		var page MWindowsData
+		if err := c.post(ctx, "getMWindows", params, &page); err != nil {
+			return nil, err
+		}
+		mwindows = append(mwindows, page.MWindows...)
+		if len(page.MWindows) == 0 || len(mwindows) >= page.Pagination.Total {
+			return mwindows, nil
+		}
+	}
+}
+
+// post sends params to the given API method and decodes the JSON answer into
+// v, retrying according to the client's retry policy
+func (c *Client) post(ctx context.Context, method string, params url.Values, v interface{}) error {
+	return c.withRetries(ctx, method, func() error {
+		return c.do(ctx, method, params, v)
+	})
+}
+
+// do makes a single call to the given API method, going through the circuit
+// breaker
+func (c *Client) do(ctx context.Context, method string, params url.Values, v interface{}) error {
+	if wait := c.RateLimitedFor(); wait > 0 {
+		c.rateLimited.Inc()
+		return fmt.Errorf("%s skipped: %w", method, &RateLimitError{RetryAfter: wait})
+	}
+
+	if !c.breaker.allow() {
+		return fmt.Errorf("%s skipped: %w", method, ErrCircuitOpen)
+	}
+	err := c.call(ctx, method, params, v)
+	// the outcome of a call given up by the caller is unknown
+	if err != nil && ctx.Err() != nil {
+		c.breaker.abort()
+		return err
+	}
+	c.mu.Lock()
+	c.called = true
+	c.lastErrorType = ErrorType(err)
+	c.mu.Unlock()
+	if from, to := c.breaker.record(isOutage(err)); from != to {
+		c.logger.Warn().Err(err).Msgf("API circuit breaker %s", to)
+	}
+	return err
+}
+
+// isOutage reports whether err tells that the API is unavailable, as opposed
+// to a call rejected by a working API: network failures, timeouts included,
+// and server errors are outages
+func isOutage(err error) bool {
+	var (
+		apiErr       *APIError
+		rateLimitErr *RateLimitError
+		statusErr    *StatusError
+		decodeErr    *DecodeError
+	)
+	switch {
+	case err == nil, errors.As(err, &apiErr), errors.As(err, &rateLimitErr), errors.As(err, &decodeErr):
+		return false
+	case errors.As(err, &statusErr):
+		return statusErr.Code >= 500
+	default:
+		return true
+	}
+}
+
+// call makes the HTTP request of an API call and decodes its answer
+func (c *Client) call(ctx context.Context, method string, params url.Values, v interface{}) (err error) {
+	params.Set("api_key", c.APIKey())
+	params.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+method, strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("cannot create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.requests.WithLabelValues(method, "error").Inc()
+		c.requestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		return fmt.Errorf("cannot call %s: %w", method, err)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.requests.WithLabelValues(method, strconv.Itoa(resp.StatusCode)).Inc()
+	c.requestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	c.setQuota(resp)
+	if err != nil {
+		return fmt.Errorf("cannot read %s response body: %w", method, err)
+	}
+	defer func() {
+		c.recordResponse(method, params, start, resp.StatusCode, body, err)
+	}()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		wait := parseRetryAfter(resp)
+		c.rateLimited.Inc()
+		c.setRateLimited(wait)
+		c.logger.Warn().Msgf("rate limited by the API, pausing calls for %s", wait)
+		return fmt.Errorf("%s failed: %w", method, &RateLimitError{RetryAfter: wait})
+	}
+
+	var status struct {
+		Stat  string    `json:"stat"`
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s failed: %w", method, &StatusError{Code: resp.StatusCode})
+		}
+		return fmt.Errorf("%s failed: %w", method, &DecodeError{Err: err})
+	}
+	if status.Stat == "fail" {
+		if status.Error == nil {
+			status.Error = &APIError{Type: "unknown"}
+		}
+		c.logger.Warn().
+			Str("type", status.Error.Type).
+			Str("parameter_name", status.Error.ParameterName).
+			Interface("passed_value", status.Error.PassedValue).
+			Str("error_message", status.Error.Message).
+			Msgf("%s failed", method)
+		return fmt.Errorf("%s failed: %w", method, status.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s failed: %w", method, &StatusError{Code: resp.StatusCode})
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%s failed: %w", method, &DecodeError{Err: err})
+	}
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package uptimerobot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestGetMonitorsStopsOnError(t *testing.T) {
+	const pages = 10
+	tests := []struct {
+		name    string
+		workers int
+		// failed is the offset of the page that fails
+		failed int
+		// hang makes the other pages answer once their call is cancelled
+		hang    bool
+		wantErr bool
+		// maxCalls is the maximum number of calls made
+		maxCalls int
+	}{
+		{name: "success", workers: 3, failed: -1, maxCalls: pages},
+		{name: "second page failed", workers: 1, failed: 50, wantErr: true, maxCalls: 2},
+		{name: "third page failed", workers: 1, failed: 100, wantErr: true, maxCalls: 3},
+		{name: "concurrent workers", workers: 3, failed: 50, hang: true, wantErr: true, maxCalls: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request, call int) {
+				offset, _ := strconv.Atoi(r.FormValue("offset"))
+				if offset == tt.failed {
+					w.Write([]byte(`{"stat":"fail","error":{"type":"internal","message":"oops"}}`))
+					return
+				}
+				if tt.hang && offset > 0 {
+					hang(w, r)
+					return
+				}
+				fmt.Fprintf(w, `{"stat":"ok","pagination":{"offset":%d,"limit":50,"total":%d},"monitors":[{"id":%d}]}`, offset, pages*50, offset+1)
+			})
+			client := New("key", Options{BaseURL: srv.URL, Workers: tt.workers, Logger: zerolog.Nop()})
+
+			monitors, err := client.GetMonitors(context.Background(), MonitorsQuery{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(monitors) != pages {
+				t.Errorf("got %d monitors, want %d", len(monitors), pages)
+			}
+			if got := int(atomic.LoadInt32(calls)); got > tt.maxCalls {
+				t.Errorf("got %d calls, want at most %d", got, tt.maxCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package uptimerobot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// APIError is the error returned by the API along with a "fail" stat
+type APIError struct {
+	Type          string      `json:"type"`
+	ParameterName string      `json:"parameter_name"`
+	PassedValue   interface{} `json:"passed_value"`
+	Message       string      `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("API error: %s", e.Type)
+	}
+	return fmt.Sprintf("API error: %s: %s", e.Type, e.Message)
+}
+
+// IsAuthError reports whether err was returned by the API because of the
+// API key
+func IsAuthError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.ParameterName == "api_key" || apiErr.Type == "not_authorized"
+}
+
+// StatusError is returned when the API answers with an unexpected HTTP status
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d %s", e.Code, http.StatusText(e.Code))
+}
+
+// DecodeError is returned when the API answer cannot be parsed
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("cannot parse JSON: %s", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitError is returned when the API rate limit is hit. No call is made
+// until RetryAfter has elapsed.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by the API, retry after %s", e.RetryAfter)
+}
+
+// ErrorType classifies the error of an API call: the type given by the API
+// for an APIError, or one of rate_limited, http_status, decode and
+// unreachable. It returns an empty string for a nil error.
+func ErrorType(err error) string {
+	var (
+		apiErr       *APIError
+		rateLimitErr *RateLimitError
+		statusErr    *StatusError
+		decodeErr    *DecodeError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &apiErr):
+		return apiErr.Type
+	case errors.As(err, &rateLimitErr):
+		return "rate_limited"
+	case errors.As(err, &statusErr):
+		return "http_status"
+	case errors.As(err, &decodeErr):
+		return "decode"
+	default:
+		return "unreachable"
+	}
+}
@@ -0,0 +1,99 @@
+package uptimerobot
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultRetryAfter is how long the API is left alone after a 429 response
+// that does not tell when to retry
+const defaultRetryAfter = time.Minute
+
+// parseRetryAfter reads the Retry-After header of resp, given either in
+// seconds or as an HTTP date
+func parseRetryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return defaultRetryAfter
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return defaultRetryAfter
+}
+
+// RateLimitedFor returns how long the client still refrains from calling the
+// API after having been rate limited
+func (c *Client) RateLimitedFor() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if d := time.Until(c.rateLimitedUntil); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// setRateLimited records that no call must be made for d
+func (c *Client) setRateLimited(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until := time.Now().Add(d); until.After(c.rateLimitedUntil) {
+		c.rateLimitedUntil = until
+	}
+}
+
+// Quota is the API rate limit status reported by the X-RateLimit-* headers of
+// the latest response
+type Quota struct {
+	// Limit is the number of requests allowed per period
+	Limit int
+	// Remaining is the number of requests left in the current period
+	Remaining int
+	// Reset is when the current period ends
+	Reset time.Time
+}
+
+// parseQuota reads the rate limit headers of resp, returning nil if they are
+// missing
+func parseQuota(resp *http.Response) *Quota {
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return nil
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return nil
+	}
+	quota := &Quota{Limit: limit, Remaining: remaining}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		quota.Reset = time.Unix(reset, 0)
+	}
+	return quota
+}
+
+// Quota returns the rate limit status reported by the latest API response,
+// nil if the API did not report it
+func (c *Client) Quota() *Quota {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.quota
+}
+
+// setQuota records the rate limit status reported by resp, if any
+func (c *Client) setQuota(resp *http.Response) {
+	quota := parseQuota(resp)
+	if quota == nil {
+		return
+	}
+	c.mu.Lock()
+	c.quota = quota
+	c.mu.Unlock()
+}
@@ -0,0 +1,139 @@
+package uptimerobot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "missing", value: "", want: defaultRetryAfter},
+		{name: "seconds", value: "30", want: 30 * time.Second},
+		{name: "zero", value: "0", want: 0},
+		{name: "past date", value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0},
+		{name: "invalid", value: "soon", want: defaultRetryAfter},
+		{name: "negative", value: "-5", want: defaultRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.value != "" {
+				resp.Header.Set("Retry-After", tt.value)
+			}
+			if got := parseRetryAfter(resp); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("future date", func(t *testing.T) {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+		if got := parseRetryAfter(resp); got <= 55*time.Second || got > time.Minute {
+			t.Errorf("parseRetryAfter() = %s, want about 1m", got)
+		}
+	})
+}
+
+// tooManyRequests answers 429 with the given Retry-After header
+func tooManyRequests(w http.ResponseWriter, retryAfter string) {
+	w.Header().Set("Retry-After", retryAfter)
+	w.WriteHeader(http.StatusTooManyRequests)
+}
+
+func TestRateLimited(t *testing.T) {
+	tests := []struct {
+		name       string
+		retry      RetryPolicy
+		wantErr    bool
+		requests   int
+		minElapsed time.Duration
+	}{
+		{
+			// the second call is skipped until Retry-After has elapsed
+			name:     "no retries",
+			retry:    RetryPolicy{MaxAttempts: 1},
+			wantErr:  true,
+			requests: 1,
+		},
+		{
+			// the retry waits for Retry-After rather than for the backoff
+			name:       "retried after Retry-After",
+			retry:      RetryPolicy{MaxAttempts: 2},
+			requests:   2,
+			minElapsed: time.Second,
+		},
+		{
+			// Retry-After exceeds the time left to retry
+			name:     "Retry-After beyond MaxElapsed",
+			retry:    RetryPolicy{MaxAttempts: 2, MaxElapsed: 500 * time.Millisecond},
+			wantErr:  true,
+			requests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, call int) {
+				if call == 1 {
+					tooManyRequests(w, "1")
+					return
+				}
+				w.Write([]byte(accountDetails))
+			})
+			c := New("u1-key", Options{BaseURL: srv.URL, Retry: tt.retry, Logger: zerolog.Nop()})
+
+			start := time.Now()
+			_, err := c.GetAccountDetails(context.Background())
+			elapsed := time.Since(start)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAccountDetails() error = %v, want error %t", err, tt.wantErr)
+			}
+			if elapsed < tt.minElapsed {
+				t.Errorf("GetAccountDetails() returned after %s, want at least %s", elapsed, tt.minElapsed)
+			}
+
+			if tt.wantErr {
+				var rateLimitErr *RateLimitError
+				if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != time.Second {
+					t.Errorf("got error %v, want a RateLimitError retrying after 1s", err)
+				}
+				if c.RateLimitedFor() <= 0 {
+					t.Error("the client is not rate limited")
+				}
+				if _, err := c.GetAccountDetails(context.Background()); !errors.As(err, &rateLimitErr) {
+					t.Errorf("call while rate limited: got %v, want a RateLimitError", err)
+				}
+			}
+			if got := int(atomic.LoadInt32(requests)); got != tt.requests {
+				t.Errorf("got %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRateLimitedNotAnOutage(t *testing.T) {
+	srv, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		tooManyRequests(w, "0")
+	})
+	c := New("u1-key", Options{
+		BaseURL: srv.URL,
+		Breaker: BreakerPolicy{Threshold: 1, Cooldown: time.Hour},
+		Logger:  zerolog.Nop(),
+	})
+	for i := 0; i < 3; i++ {
+		c.GetAccountDetails(context.Background())
+	}
+	if got := c.breaker.current(); got != breakerClosed {
+		t.Errorf("breaker %s after 429 answers, want %s", got, breakerClosed)
+	}
+}
@@ -0,0 +1,65 @@
+package uptimerobot
+
+import (
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Response is a raw answer of the API, kept to investigate how the API data
+// turns into metrics
+type Response struct {
+	// Method is the API method called
+	Method string
+	// Params are the parameters of the call, without the API key
+	Params url.Values
+	// Time is the date of the call, and Duration how long it took
+	Time     time.Time
+	Duration time.Duration
+	// StatusCode is the HTTP status code of the answer
+	StatusCode int
+	// Body is the raw body of the answer
+	Body []byte
+	// Error is the error of the call or of the decoding of its answer, if
+	// any
+	Error string
+}
+
+// LatestResponses returns the latest response of each API method called, by
+// method name. Only the last page of paginated calls is kept.
+func (c *Client) LatestResponses() []Response {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	responses := make([]Response, 0, len(c.responses))
+	for _, resp := range c.responses {
+		responses = append(responses, resp)
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		return responses[i].Method < responses[j].Method
+	})
+	return responses
+}
+
+// recordResponse keeps the answer of a call to method made at start
+func (c *Client) recordResponse(method string, params url.Values, start time.Time, statusCode int, body []byte, err error) {
+	resp := Response{
+		Method:     method,
+		Params:     make(url.Values, len(params)),
+		Time:       start,
+		Duration:   time.Since(start),
+		StatusCode: statusCode,
+		Body:       body,
+	}
+	for name, values := range params {
+		if name != "api_key" {
+			resp.Params[name] = values
+		}
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	c.responses[method] = resp
+	c.mu.Unlock()
+}
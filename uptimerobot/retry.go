@@ -0,0 +1,106 @@
+package uptimerobot
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// initialBackoff is the wait before the first retry, doubled on each
+	// subsequent attempt up to maxBackoff
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+)
+
+var (
+	// jitter randomizes the backoffs, it is seeded so that several exporter
+	// instances do not share the same sequence
+	jitterMu sync.Mutex
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// RetryPolicy tells how failed API calls are retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of calls made, 1 disables retries
+	MaxAttempts int
+	// MaxElapsed is the time after which no more retries are attempted, 0
+	// means no limit
+	MaxElapsed time.Duration
+}
+
+// backoff returns the wait before the given retry, exponentially growing with
+// a random jitter so that concurrent callers do not retry in sync
+func backoff(retry int) time.Duration {
+	d := initialBackoff << uint(retry-1)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return d/2 + time.Duration(jitter.Int63n(int64(d/2)+1))
+}
+
+// retryable reports whether the call made with ctx that returned err is worth
+// retrying: network failures, timeouts and server errors are, errors reported
+// by the API are not. Nothing is retried once ctx is done, but the timeouts of
+// the HTTP client, which also match context.DeadlineExceeded, are.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return false
+	}
+
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= 500
+	}
+
+	var decodeErr *DecodeError
+	return !errors.As(err, &decodeErr)
+}
+
+// withRetries calls fn until it succeeds, returns a non retryable error, or
+// the retry policy is exhausted
+func (c *Client) withRetries(ctx context.Context, method string, fn func() error) error {
+	var deadline time.Time
+	if c.retry.MaxElapsed > 0 {
+		deadline = time.Now().Add(c.retry.MaxElapsed)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= c.retry.MaxAttempts || !retryable(ctx, err) {
+			return err
+		}
+
+		wait := backoff(attempt)
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > wait {
+			wait = rateLimitErr.RetryAfter
+		}
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			return err
+		}
+		c.logger.Debug().Err(err).Msgf("%s attempt %d failed, retrying in %s", method, attempt, wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package uptimerobot
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// accountDetails is a successful getAccountDetails answer
+const accountDetails = `{"stat":"ok","account":{"email":"ops@example.com","monitor_limit":50}}`
+
+// newTestServer returns a server answering each API call with handler, and
+// the counter of the calls it received
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, call int)) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, int(atomic.AddInt32(&calls, 1)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// hang answers once the client gave up on the request
+func hang(w http.ResponseWriter, r *http.Request) {
+	// the server notices that the connection is closed once the body is read
+	io.Copy(ioutil.Discard, r.Body)
+	select {
+	case <-r.Context().Done():
+	case <-time.After(5 * time.Second):
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, r *http.Request, call int)
+		wantErr bool
+		calls   int
+	}{
+		{
+			name: "success",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				w.Write([]byte(accountDetails))
+			},
+			calls: 1,
+		},
+		{
+			name: "timeout then success",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				if call < 3 {
+					hang(w, r)
+					return
+				}
+				w.Write([]byte(accountDetails))
+			},
+			calls: 3,
+		},
+		{
+			name: "timeouts",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				hang(w, r)
+			},
+			wantErr: true,
+			calls:   3,
+		},
+		{
+			name: "server errors",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantErr: true,
+			calls:   3,
+		},
+		{
+			name: "client error",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantErr: true,
+			calls:   1,
+		},
+		{
+			name: "API error",
+			handler: func(w http.ResponseWriter, r *http.Request, call int) {
+				w.Write([]byte(`{"stat":"fail","error":{"type":"invalid_parameter","parameter_name":"api_key"}}`))
+			},
+			wantErr: true,
+			calls:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := newTestServer(t, tt.handler)
+			c := New("u1-key", Options{
+				BaseURL: srv.URL,
+				Timeout: 50 * time.Millisecond,
+				Retry:   RetryPolicy{MaxAttempts: 3},
+				Logger:  zerolog.Nop(),
+			})
+			_, err := c.GetAccountDetails(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAccountDetails() error = %v, want error %t", err, tt.wantErr)
+			}
+			if got := int(atomic.LoadInt32(calls)); got != tt.calls {
+				t.Errorf("got %d calls, want %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRetriesStopWithContext(t *testing.T) {
+	srv, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		hang(w, r)
+	})
+	c := New("u1-key", Options{
+		BaseURL: srv.URL,
+		Retry:   RetryPolicy{MaxAttempts: 3},
+		Logger:  zerolog.Nop(),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetAccountDetails(ctx); err == nil {
+		t.Fatal("GetAccountDetails() succeeded, want an error")
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("got %d calls once the context is done, want 1", got)
+	}
+}
@@ -0,0 +1,265 @@
+package uptimerobot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AccountDetails struct {
+	Stat    string `json:"stat"`
+	Account struct {
+		Email                  string `json:"email"`
+		UserID                 int    `json:"user_id"`
+		Firstname              string `json:"firstname"`
+		SmsCredits             int    `json:"sms_credits"`
+		PaymentProcessor       int    `json:"payment_processor"`
+		PaymentPeriod          int    `json:"payment_period"`
+		SubscriptionExpiryDate Date   `json:"subscription_expiry_date"`
+		MonitorLimit           int    `json:"monitor_limit"`
+		MonitorInterval        int    `json:"monitor_interval"`
+		UpMonitors             int    `json:"up_monitors"`
+		DownMonitors           int    `json:"down_monitors"`
+		PausedMonitors         int    `json:"paused_monitors"`
+	} `json:"account"`
+}
+
+// Date is a date returned by the API. It is the zero time when the API
+// returns null or an empty string, as for the subscription expiry date of
+// free plans.
+type Date struct {
+	time.Time
+}
+
+// dateLayouts are the formats of the dates returned by the API
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting Unix timestamps and
+// formatted dates
+func (d *Date) UnmarshalJSON(data []byte) error {
+	raw := strings.Trim(string(data), `"`)
+	if raw == "" || raw == "null" || raw == "0" {
+		d.Time = time.Time{}
+		return nil
+	}
+	if ts, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		d.Time = time.Unix(ts, 0)
+		return nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			d.Time = t
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse date %s", data)
+}
+
+type MonitorsData struct {
+	Stat       string `json:"stat"`
+	Pagination struct {
+		Offset int `json:"offset"`
+		Limit  int `json:"limit"`
+		Total  int `json:"total"`
+	} `json:"pagination"`
+	Monitors []Monitor `json:"monitors"`
+}
+
+// Monitor types
+const (
+	MonitorTypeHTTP      = 1
+	MonitorTypeKeyword   = 2
+	MonitorTypePing      = 3
+	MonitorTypePort      = 4
+	MonitorTypeHeartbeat = 5
+)
+
+// Sub types of the port monitors, telling the checked service
+const (
+	PortSubTypeHTTP   = 1
+	PortSubTypeHTTPS  = 2
+	PortSubTypeFTP    = 3
+	PortSubTypeSMTP   = 4
+	PortSubTypePOP3   = 5
+	PortSubTypeIMAP   = 6
+	PortSubTypeCustom = 99
+)
+
+// Keyword types of the keyword monitors
+const (
+	KeywordTypeExists    = 1
+	KeywordTypeNotExists = 2
+)
+
+type Monitor struct {
+	ID             int    `json:"id"`
+	FriendlyName   string `json:"friendly_name"`
+	URL            string `json:"url"`
+	Type           int    `json:"type"`
+	SubType        string `json:"sub_type"`
+	KeywordType    int    `json:"keyword_type"`
+	KeywordValue   string `json:"keyword_value"`
+	HTTPUsername   string `json:"http_username"`
+	HTTPPassword   string `json:"http_password"`
+	Port           string `json:"port"`
+	Interval       int    `json:"interval"`
+	Status         int    `json:"status"`
+	CreateDatetime int    `json:"create_datetime"`
+	ResponseTimes  []struct {
+		Datetime int `json:"datetime"`
+		Value    int `json:"value"`
+	} `json:"response_times"`
+	AverageResponseTime json.Number `json:"average_response_time"`
+	SSL                 *SSLInfo    `json:"ssl"`
+	// CustomUptimeRatio holds the uptime percentages over the periods
+	// requested with MonitorsQuery.CustomUptimeRatios, separated by dashes
+	CustomUptimeRatio string `json:"custom_uptime_ratio"`
+	// CustomDownDurations holds the downtimes over the same periods, in
+	// seconds, returned when requested with MonitorsQuery.CustomDownDurations
+	CustomDownDurations string `json:"custom_down_durations"`
+	// AllTimeUptimeRatio is the uptime percentage since the monitor was
+	// created, returned when requested with MonitorsQuery.AllTimeUptime
+	AllTimeUptimeRatio string `json:"all_time_uptime_ratio"`
+	// AllTimeUptimeDurations holds the up, down and paused durations since the
+	// monitor was created, in seconds and separated by dashes
+	AllTimeUptimeDurations string `json:"all_time_uptime_durations"`
+	// Tags are the tags of the monitor, returned when requested with
+	// MonitorsQuery.Tags
+	Tags []Tag `json:"tags"`
+	// Logs are the latest events of the monitor, newest first, returned when
+	// requested with MonitorsQuery.Logs
+	Logs []Log `json:"logs"`
+}
+
+// Tag is a tag set on monitors to group them
+type Tag struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// Log types
+const (
+	LogTypeDown    = 1
+	LogTypeUp      = 2
+	LogTypePaused  = 99
+	LogTypeStarted = 98
+)
+
+// Log is an event of a monitor, such as going down or up
+type Log struct {
+	ID       int `json:"id"`
+	Type     int `json:"type"`
+	Datetime int `json:"datetime"`
+	// Duration is how long the monitor stayed in the state, in seconds
+	Duration int `json:"duration"`
+}
+
+// UptimeDurations holds the time spent by a monitor in each state, in seconds
+type UptimeDurations struct {
+	Up     float64
+	Down   float64
+	Paused float64
+}
+
+// AllTimeDurations returns the up, down and paused durations since the
+// monitor was created, nil if they were not returned
+func (m Monitor) AllTimeDurations() (*UptimeDurations, error) {
+	values, err := splitFloats(m.AllTimeUptimeDurations)
+	if err != nil || values == nil {
+		return nil, err
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("cannot parse %q: expected 3 durations", m.AllTimeUptimeDurations)
+	}
+	return &UptimeDurations{Up: values[0], Down: values[1], Paused: values[2]}, nil
+}
+
+// CustomUptimeRatios returns the uptime percentages over the periods
+// requested with MonitorsQuery.CustomUptimeRatios, in the same order
+func (m Monitor) CustomUptimeRatios() ([]float64, error) {
+	return splitFloats(m.CustomUptimeRatio)
+}
+
+// DownDurations returns the downtimes over the periods requested with
+// MonitorsQuery.CustomUptimeRatios, in seconds and in the same order
+func (m Monitor) DownDurations() ([]float64, error) {
+	return splitFloats(m.CustomDownDurations)
+}
+
+// splitFloats parses the dash-separated numbers the API uses for values
+// computed over several periods
+func splitFloats(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	fields := strings.Split(s, "-")
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q: %w", s, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// SSLInfo describes the SSL certificate checked by a monitor, returned when
+// requested with MonitorsQuery.SSL
+type SSLInfo struct {
+	Brand   string `json:"brand"`
+	Product string `json:"product"`
+	// Expires is the expiry date of the certificate as a Unix timestamp
+	Expires int64 `json:"expires"`
+}
+
+type MWindowsData struct {
+	Stat       string `json:"stat"`
+	Pagination struct {
+		Offset int `json:"offset"`
+		Limit  int `json:"limit"`
+		Total  int `json:"total"`
+	} `json:"pagination"`
+	MWindows []MWindow `json:"mwindows"`
+}
+
+// Maintenance window types
+const (
+	MWindowTypeOnce    = 1
+	MWindowTypeDaily   = 2
+	MWindowTypeWeekly  = 3
+	MWindowTypeMonthly = 4
+)
+
+// MWindow is a maintenance window, during which the monitors are paused
+type MWindow struct {
+	ID           int    `json:"id"`
+	User         int    `json:"user"`
+	Type         int    `json:"type"`
+	FriendlyName string `json:"friendly_name"`
+	// StartTime is a Unix timestamp for windows of type MWindowTypeOnce, and
+	// a time of day formatted as HH:mm for recurring windows
+	StartTime json.RawMessage `json:"start_time"`
+	// Duration is the length of the window in minutes
+	Duration int `json:"duration"`
+	// Value holds the days of the week or of the month of recurring windows
+	Value  string `json:"value"`
+	Status int    `json:"status"`
+}
+
+// Start returns the start of the window: the Unix timestamp of a one-time
+// window, or the number of seconds after midnight of a recurring one
+func (w MWindow) Start() (float64, error) {
+	start := strings.Trim(string(w.StartTime), `"`)
+	if w.Type == MWindowTypeOnce {
+		return strconv.ParseFloat(start, 64)
+	}
+	t, err := time.Parse("15:04", start)
+	if err != nil {
+		return 0, err
+	}
+	return float64(t.Hour()*3600 + t.Minute()*60), nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eze-kiel/uptimerobot-exporter/logger"
+	"github.com/prometheus/exporter-toolkit/web"
+	"gopkg.in/yaml.v2"
+)
+
+// startServers serves handler on every address the exporter listens on, with
+// one server per listener. The servers fail the exporter when they stop
+// unexpectedly.
+func (a *app) startServers(handler http.Handler) ([]*http.Server, error) {
+	var tlsConfig *tls.Config
+	if a.tlsCertFile != "" {
+		var err error
+		if tlsConfig, err = a.tlsConfig(); err != nil {
+			return nil, err
+		}
+	}
+	listeners, err := a.listeners()
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]*http.Server, 0, len(listeners))
+	for _, l := range listeners {
+		srv := &http.Server{
+			Handler:           handler,
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: a.readHeaderTimeout,
+			ReadTimeout:       a.readTimeout,
+			WriteTimeout:      a.writeTimeout,
+			IdleTimeout:       a.idleTimeout,
+		}
+		servers = append(servers, srv)
+		a.logger.Info().Msgf("listening on %s", l.Addr())
+		go func(srv *http.Server, l net.Listener) {
+			if err := a.serveHTTP(srv, l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.logger.Fatal().Err(err).Msg("Metrics server failed")
+			}
+		}(srv, l)
+	}
+	return servers, nil
+}
+
+// serveHTTP serves srv on l as set by -web.config.file, or over HTTPS when
+// srv has a TLS configuration, and over plain HTTP otherwise
+func (a *app) serveHTTP(srv *http.Server, l net.Listener) error {
+	defer l.Close()
+
+	if a.webConfigFile != "" {
+		return web.Serve(l, srv, a.webConfigFile, logger.NewKitLogger(a.logger))
+	}
+	if srv.TLSConfig == nil {
+		return srv.Serve(l)
+	}
+	return srv.ServeTLS(l, "", "")
+}
+
+// listeners listens on the Unix domain socket -web.listen-socket when it is
+// set, and otherwise on every -web.listen-address, or on -ip and -p
+func (a *app) listeners() ([]net.Listener, error) {
+	if a.listenSocket != "" {
+		l, err := a.listenSocketFile()
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{l}, nil
+	}
+
+	addresses := a.listenAddresses
+	if len(addresses) == 0 {
+		addresses = []string{net.JoinHostPort(a.address, a.port)}
+	}
+	listeners := make([]net.Listener, 0, len(addresses))
+	for _, addr := range addresses {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// listenSocketFile listens on the Unix domain socket -web.listen-socket
+func (a *app) listenSocketFile() (net.Listener, error) {
+	// remove the socket left behind by a previous run that did not stop
+	// cleanly, but nothing else
+	if info, err := os.Lstat(a.listenSocket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(a.listenSocket); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", a.listenSocket)
+}
+
+// tlsConfig returns the TLS configuration of the metrics server
+func (a *app) tlsConfig() (*tls.Config, error) {
+	certs := &certificate{certFile: a.tlsCertFile, keyFile: a.tlsKeyFile}
+	// fail at startup rather than on the first connection
+	if _, err := certs.get(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return certs.get()
+		},
+	}
+
+	if a.tlsClientCAFile != "" {
+		content, err := ioutil.ReadFile(a.tlsClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no PEM certificate found in %s", a.tlsClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// certificate is a TLS certificate loaded from files, which are loaded again
+// when they change so that a renewed certificate is served without restarting
+type certificate struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// get returns the certificate, loading it again if its files changed since
+// the last load. The previous certificate is kept if the files cannot be
+// loaded, as they may be in the middle of being replaced.
+func (c *certificate) get() (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modTime, err := latestModTime(c.certFile, c.keyFile)
+	if err != nil && c.cert == nil {
+		return nil, err
+	}
+	if err != nil || !modTime.After(c.modTime) {
+		return c.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		if c.cert == nil {
+			return nil, fmt.Errorf("cannot load TLS certificate: %w", err)
+		}
+		return c.cert, nil
+	}
+	c.cert = &cert
+	c.modTime = modTime
+	return c.cert, nil
+}
+
+// latestModTime returns the latest modification time of the given files
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// webConfigUsers reports whether the web configuration file located at path
+// declares basic authentication users
+func webConfigUsers(path string) (bool, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	var cfg struct {
+		Users map[string]string `yaml:"basic_auth_users"`
+	}
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return false, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	return len(cfg.Users) > 0, nil
+}